@@ -10,11 +10,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jonx8/chat-service/internal/auth"
 	"github.com/jonx8/chat-service/internal/config"
 	"github.com/jonx8/chat-service/internal/database"
+	"github.com/jonx8/chat-service/internal/events"
 	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/presence"
 	"github.com/jonx8/chat-service/internal/repositories"
 	"github.com/jonx8/chat-service/internal/services"
+	"github.com/jonx8/chat-service/internal/storage"
+	"github.com/jonx8/chat-service/internal/thumbnails"
 )
 
 func main() {
@@ -22,6 +28,18 @@ func main() {
 
 	slog.Info(fmt.Sprintf("Starting %s:%s...", cfg.AppName, cfg.AppVersion))
 
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize token verifier", "error", err)
+		os.Exit(1)
+	}
+
+	blobStore, err := storage.New(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize blob store", "error", err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -43,29 +61,182 @@ func main() {
 	gormDB := db.Gorm()
 	chatRepo := repositories.NewChatRepository(gormDB)
 	messageRepo := repositories.NewMessageRepository(gormDB)
+	searchRepo := repositories.NewSearchRepository(gormDB)
+	memberRepo := repositories.NewMemberRepository(gormDB)
+	inviteRepo := repositories.NewInviteRepository(gormDB)
+	reactionRepo := repositories.NewReactionRepository(gormDB)
+	pinRepo := repositories.NewPinRepository(gormDB)
+	attachmentRepo := repositories.NewAttachmentRepository(gormDB)
+	scheduledRepo := repositories.NewScheduledMessageRepository(gormDB)
+	idempotencyRepo := repositories.NewIdempotencyRepository(gormDB)
+
+	// Group chats created before owners were recorded may have no member able
+	// to manage them, or no members at all. They are handed to the configured
+	// user, who can then add members and pass the ownership on.
+	if cfg.OrphanedChatsOwner != "" {
+		adopted, err := chatRepo.AdoptOrphaned(ctx, &models.User{ID: cfg.OrphanedChatsOwner})
+		if err != nil {
+			slog.Error("Failed to adopt orphaned chats", "error", err)
+		} else if adopted > 0 {
+			slog.Info("Adopted orphaned chats", "count", adopted, "owner", cfg.OrphanedChatsOwner)
+		}
+	}
+
+	broker := events.NewBroker()
+	pubSub := db.NewPubSub(broker, messageRepo)
+
+	// Presence is tracked by every instance for its own connections and
+	// exchanged through pubSub; the merged state is published to the local
+	// broker.
+	presenceTracker := presence.NewTracker(
+		broker,
+		pubSub,
+		time.Duration(cfg.PresenceTTL)*time.Second,
+		time.Duration(cfg.PresenceAwayAfter)*time.Second,
+	)
+	pubSub.HandlePresence(presenceTracker)
+
+	trackerCtx, stopTracker := context.WithCancel(context.Background())
+	trackerDone := make(chan struct{})
+	go func() {
+		defer close(trackerDone)
+		presenceTracker.Run(trackerCtx)
+	}()
+
+	listenerCtx, stopListener := context.WithCancel(context.Background())
+	listenerDone := make(chan struct{})
+	go func() {
+		defer close(listenerDone)
+		pubSub.Run(listenerCtx)
+	}()
+
+	thumbnailWorker := thumbnails.NewWorker(attachmentRepo, blobStore, cfg.ThumbnailMaxDimension)
+
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		thumbnailWorker.Run(workerCtx)
+	}()
+
+	blobSweeper := services.NewBlobSweeper(attachmentRepo, blobStore)
+
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	sweeperDone := make(chan struct{})
+	go func() {
+		defer close(sweeperDone)
+		blobSweeper.Run(sweeperCtx)
+	}()
+
+	// Scheduled messages are published through pubSub like any other message,
+	// so subscribers of every instance receive them.
+	dispatcher := services.NewScheduledMessageDispatcher(scheduledRepo, memberRepo, pubSub)
+
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(dispatcherCtx)
+	}()
+
+	reaper := services.NewRetentionReaper(messageRepo)
+
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	reaperDone := make(chan struct{})
+	go func() {
+		defer close(reaperDone)
+		reaper.Run(reaperCtx)
+	}()
+
+	idempotencyWindow := time.Duration(cfg.IdempotencyWindow) * time.Second
+	idempotencyPruner := services.NewIdempotencyPruner(idempotencyRepo, idempotencyWindow)
+
+	prunerCtx, stopPruner := context.WithCancel(context.Background())
+	prunerDone := make(chan struct{})
+	go func() {
+		defer close(prunerDone)
+		idempotencyPruner.Run(prunerCtx)
+	}()
 
-	chatService := services.NewChatService(chatRepo)
-	messageService := services.NewMessageService(messageRepo)
+	chatService := services.NewChatService(chatRepo, memberRepo, reactionRepo, pubSub)
+	messageService := services.NewMessageService(messageRepo, scheduledRepo, memberRepo, reactionRepo, pubSub)
+	searchService := services.NewSearchService(searchRepo)
+	memberService := services.NewMemberService(memberRepo, pubSub)
+	inviteService := services.NewInviteService(inviteRepo, memberRepo)
+	pinService := services.NewPinService(pinRepo, memberRepo, cfg.MaxPinnedMessages)
+	attachmentService := services.NewAttachmentService(attachmentRepo, memberRepo, blobStore, services.AttachmentLimits{
+		MaxSize:      int64(cfg.AttachmentMaxSize),
+		AllowedTypes: cfg.AttachmentAllowedTypes,
+	})
+	presenceService := services.NewPresenceService(memberRepo, presenceTracker)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, idempotencyWindow)
 
 	chatHandler := handlers.NewChatHandler(chatService)
 	messageHandler := handlers.NewMessageHandler(messageService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	memberHandler := handlers.NewMemberHandler(memberService)
+	inviteHandler := handlers.NewInviteHandler(inviteService)
+	pinHandler := handlers.NewPinHandler(pinService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, int64(cfg.AttachmentMaxSize))
+	presenceHandler := handlers.NewPresenceHandler(presenceService)
+	webSocketHandler := handlers.NewWebSocketHandler(chatService, presenceService, broker)
+	eventStreamHandler := handlers.NewEventStreamHandler(chatService, messageService, presenceService, broker)
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("POST /chats", chatHandler.CreateChat)
+	mux.HandleFunc("GET /chats", chatHandler.ListChats)
+	mux.HandleFunc("POST /chats", handlers.IdempotencyMiddleware(idempotencyService, chatHandler.CreateChat))
 	mux.HandleFunc("GET /chats/{id}", chatHandler.GetChat)
 	mux.HandleFunc("DELETE /chats/{id}", chatHandler.DeleteChat)
+	mux.HandleFunc("POST /chats/{id}/read", chatHandler.MarkRead)
+	mux.HandleFunc("PUT /chats/{id}/retention", chatHandler.UpdateRetention)
+	mux.HandleFunc("PUT /dm/{userId}", chatHandler.OpenDirectChat)
+	mux.HandleFunc("GET /chats/{id}/ws", webSocketHandler.ServeChat)
+	mux.HandleFunc("GET /chats/{id}/events", eventStreamHandler.StreamChat)
+	mux.HandleFunc("POST /chats/{id}/typing", presenceHandler.Typing)
+	mux.HandleFunc("GET /chats/{id}/presence", presenceHandler.ListPresence)
 
-	mux.HandleFunc("POST /chats/{id}/messages", messageHandler.CreateMessage)
+	mux.HandleFunc("GET /chats/{id}/members", memberHandler.ListMembers)
+	mux.HandleFunc("POST /chats/{id}/members", memberHandler.AddMember)
+	mux.HandleFunc("PATCH /chats/{id}/members/{userId}", memberHandler.UpdateMember)
+	mux.HandleFunc("DELETE /chats/{id}/members/{userId}", memberHandler.RemoveMember)
+
+	mux.HandleFunc("GET /chats/{id}/invites", inviteHandler.ListInvites)
+	mux.HandleFunc("POST /chats/{id}/invites", inviteHandler.CreateInvite)
+	mux.HandleFunc("DELETE /chats/{id}/invites/{inviteId}", inviteHandler.RevokeInvite)
+	mux.HandleFunc("POST /invites/{token}/accept", inviteHandler.AcceptInvite)
+
+	mux.HandleFunc("PUT /chats/{id}/pins/{messageId}", pinHandler.PinMessage)
+	mux.HandleFunc("DELETE /chats/{id}/pins/{messageId}", pinHandler.UnpinMessage)
+
+	mux.HandleFunc("POST /chats/{id}/attachments", attachmentHandler.UploadAttachment)
+	mux.HandleFunc("GET /chats/{id}/attachments/{attachmentId}", attachmentHandler.DownloadAttachment)
+	mux.HandleFunc("GET /chats/{id}/attachments/{attachmentId}/thumbnail", attachmentHandler.DownloadThumbnail)
+
+	mux.HandleFunc("POST /chats/{id}/messages", handlers.IdempotencyMiddleware(idempotencyService, messageHandler.CreateMessage))
+	mux.HandleFunc("GET /chats/{id}/messages", messageHandler.ListMessages)
+	mux.HandleFunc("PATCH /chats/{id}/messages/{messageId}", messageHandler.UpdateMessage)
+	mux.HandleFunc("DELETE /chats/{id}/messages/{messageId}", messageHandler.DeleteMessage)
+	mux.HandleFunc("GET /chats/{id}/messages/{messageId}/revisions", messageHandler.ListRevisions)
+	mux.HandleFunc("GET /chats/{id}/messages/{messageId}/thread", messageHandler.ListThread)
+	mux.HandleFunc("PUT /chats/{id}/messages/{messageId}/reactions/{emoji}", messageHandler.AddReaction)
+	mux.HandleFunc("DELETE /chats/{id}/messages/{messageId}/reactions/{emoji}", messageHandler.RemoveReaction)
+
+	mux.HandleFunc("GET /search", searchHandler.Search)
 
 	server := &http.Server{
 		Addr:         ":8080",
-		Handler:      mux,
+		Handler:      handlers.AuthMiddleware(verifier, mux),
 		ReadTimeout:  20 * time.Second,
 		WriteTimeout: 20 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Hijacked WebSocket connections are not tracked by Shutdown and event
+	// streams never become idle on their own, so close every subscription to
+	// let live connections finish.
+	server.RegisterOnShutdown(broker.Close)
+
 	serverErrors := make(chan error, 1)
 	go func() {
 		slog.Info("Starting HTTP server...", "addr", server.Addr)
@@ -91,5 +262,33 @@ func main() {
 		}
 	}
 
+	slog.Info("Stopping scheduled message dispatcher...")
+	stopDispatcher()
+	<-dispatcherDone
+
+	slog.Info("Stopping retention reaper...")
+	stopReaper()
+	<-reaperDone
+
+	slog.Info("Stopping idempotency key pruner...")
+	stopPruner()
+	<-prunerDone
+
+	slog.Info("Stopping notification listener...")
+	stopListener()
+	<-listenerDone
+
+	slog.Info("Stopping thumbnail worker...")
+	stopWorker()
+	<-workerDone
+
+	slog.Info("Stopping blob sweeper...")
+	stopSweeper()
+	<-sweeperDone
+
+	slog.Info("Stopping presence tracker...")
+	stopTracker()
+	<-trackerDone
+
 	slog.Info("Server stopped")
 }
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jonx8/chat-service/internal/config"
+)
+
+const clockSkew = 30 * time.Second
+
+// Limits of the users table. Longer subjects cannot identify a user and are
+// rejected; longer names are only for display and are cut.
+const (
+	maxSubjectLength = 255
+	maxNameLength    = 200
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrNoKeysConfigured = errors.New("no JWT verification keys configured")
+)
+
+type claims struct {
+	jwt.RegisteredClaims
+	Name string `json:"name"`
+}
+
+// Verifier validates HS256 and RS256 bearer tokens. RS256 keys come from a PEM
+// file and/or a local JWKS file; tokens carrying a kid header are matched
+// against the JWKS keys.
+type Verifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	jwks      map[string]*rsa.PublicKey
+	parser    *jwt.Parser
+}
+
+func NewVerifier(cfg *config.Config) (*Verifier, error) {
+	verifier := &Verifier{jwks: make(map[string]*rsa.PublicKey)}
+
+	var methods []string
+
+	if cfg.JWTSecret != "" {
+		verifier.secret = []byte(cfg.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if cfg.JWTPublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read public key: %w", err)
+		}
+
+		if verifier.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+	}
+
+	if cfg.JWTJWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.jwks = keys
+	}
+
+	if verifier.publicKey != nil || len(verifier.jwks) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, ErrNoKeysConfigured
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	}
+
+	if cfg.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+
+	if cfg.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(cfg.JWTAudience))
+	}
+
+	verifier.parser = jwt.NewParser(options...)
+
+	return verifier, nil
+}
+
+// Verify checks the token signature and claims and returns the identity of
+// its subject.
+func (v *Verifier) Verify(token string) (Identity, error) {
+	var parsed claims
+	if _, err := v.parser.ParseWithClaims(token, &parsed, v.key); err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if parsed.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	if utf8.RuneCountInString(parsed.Subject) > maxSubjectLength {
+		return Identity{}, fmt.Errorf("%w: subject is too long", ErrInvalidToken)
+	}
+
+	return Identity{Subject: parsed.Subject, Name: truncate(parsed.Name, maxNameLength)}, nil
+}
+
+// truncate cuts s to at most n characters.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+
+	case jwt.SigningMethodRS256.Alg():
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			if key, found := v.jwks[kid]; found {
+				return key, nil
+			}
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		if v.publicKey != nil {
+			return v.publicKey, nil
+		}
+
+		// A token without kid can only be matched against an unambiguous set.
+		if len(v.jwks) == 1 {
+			for _, key := range v.jwks {
+				return key, nil
+			}
+		}
+
+		return nil, errors.New("token has no key id")
+	}
+
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS reads the RSA signing keys of a JWKS document. Keys of other types
+// or uses are skipped.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := jwk.rsaPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("parse jwks key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (jwk jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("decode modulus: %w", err)
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("decode exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 3 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
@@ -0,0 +1,158 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jonx8/chat-service/internal/auth"
+	"github.com/jonx8/chat-service/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-secret"
+
+func signHS256(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":  "alice",
+		"name": "Alice",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	document := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	data, err := json.Marshal(document)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	// Arrange
+	verifier, err := auth.NewVerifier(&config.Config{JWTSecret: testSecret})
+	require.NoError(t, err)
+
+	// Act
+	identity, err := verifier.Verify(signHS256(t, testSecret, validClaims()))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Subject)
+	assert.Equal(t, "Alice", identity.Name)
+}
+
+func TestVerifier_TruncatesLongNames(t *testing.T) {
+	// Arrange
+	verifier, err := auth.NewVerifier(&config.Config{JWTSecret: testSecret})
+	require.NoError(t, err)
+
+	claims := validClaims()
+	claims["name"] = strings.Repeat("я", 250)
+
+	// Act
+	identity, err := verifier.Verify(signHS256(t, testSecret, claims))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("я", 200), identity.Name)
+}
+
+func TestVerifier_RejectsInvalidTokens(t *testing.T) {
+	verifier, err := auth.NewVerifier(&config.Config{JWTSecret: testSecret, JWTIssuer: "issuer"})
+	require.NoError(t, err)
+
+	withIssuer := func(claims jwt.MapClaims) jwt.MapClaims {
+		claims["iss"] = "issuer"
+		return claims
+	}
+
+	expired := withIssuer(validClaims())
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	noSubject := withIssuer(validClaims())
+	delete(noSubject, "sub")
+
+	noExpiry := withIssuer(validClaims())
+	delete(noExpiry, "exp")
+
+	longSubject := withIssuer(validClaims())
+	longSubject["sub"] = strings.Repeat("a", 256)
+
+	tests := map[string]string{
+		"wrong secret":  signHS256(t, "other-secret", withIssuer(validClaims())),
+		"wrong issuer":  signHS256(t, testSecret, validClaims()),
+		"expired":       signHS256(t, testSecret, expired),
+		"no subject":    signHS256(t, testSecret, noSubject),
+		"no expiration": signHS256(t, testSecret, noExpiry),
+		"long subject":  signHS256(t, testSecret, longSubject),
+		"garbage":       "not-a-token",
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(token)
+			assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		})
+	}
+}
+
+func TestVerifier_RS256FromJWKS(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier, err := auth.NewVerifier(&config.Config{JWTJWKSFile: writeJWKS(t, "key-1", &key.PublicKey)})
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	// Act
+	identity, err := verifier.Verify(signed)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Subject)
+
+	_, err = verifier.Verify(signHS256(t, testSecret, validClaims()))
+	assert.ErrorIs(t, err, auth.ErrInvalidToken, "HS256 must be rejected without a configured secret")
+}
+
+func TestNewVerifier_RequiresKeys(t *testing.T) {
+	_, err := auth.NewVerifier(&config.Config{})
+	assert.ErrorIs(t, err, auth.ErrNoKeysConfigured)
+}
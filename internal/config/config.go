@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -25,6 +26,38 @@ type Config struct {
 	DBConnMaxLifetime int
 	DBMaxOpenConns    int
 	DBMaxIdleConns    int
+
+	// Authentication
+	JWTSecret        string
+	JWTPublicKeyFile string
+	JWTJWKSFile      string
+	JWTIssuer        string
+	JWTAudience      string
+
+	// Limits
+	MaxPinnedMessages int
+
+	// Attachments
+	StorageBackend         string
+	StorageDir             string
+	S3Endpoint             string
+	S3Region               string
+	S3Bucket               string
+	S3AccessKeyID          string
+	S3SecretAccessKey      string
+	AttachmentMaxSize      int
+	AttachmentAllowedTypes []string
+	ThumbnailMaxDimension  int
+
+	// Presence, in seconds
+	PresenceTTL       int
+	PresenceAwayAfter int
+
+	// Idempotency, in seconds
+	IdempotencyWindow int
+
+	// Ownership
+	OrphanedChatsOwner string
 }
 
 func Load() *Config {
@@ -47,6 +80,40 @@ func Load() *Config {
 		DBConnMaxLifetime: getIntEnv("DB_CONN_MAX_LIFETIME", 3600),
 		DBMaxOpenConns:    getIntEnv("DB_MAX_OPEN_CONNS", 20),
 		DBMaxIdleConns:    getIntEnv("DB_MAX_IDLE_CONNS", 5),
+
+		// Authentication
+		JWTSecret:        getEnv("JWT_SECRET", ""),
+		JWTPublicKeyFile: getEnv("JWT_PUBLIC_KEY_FILE", ""),
+		JWTJWKSFile:      getEnv("JWT_JWKS_FILE", ""),
+		JWTIssuer:        getEnv("JWT_ISSUER", ""),
+		JWTAudience:      getEnv("JWT_AUDIENCE", ""),
+
+		// Limits
+		MaxPinnedMessages: getIntEnv("MAX_PINNED_MESSAGES", 50),
+
+		// Attachments
+		StorageBackend:    getEnv("STORAGE_BACKEND", "local"),
+		StorageDir:        getEnv("STORAGE_DIR", "data/attachments"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		AttachmentMaxSize: getIntEnv("ATTACHMENT_MAX_SIZE", 10<<20),
+		AttachmentAllowedTypes: getListEnv("ATTACHMENT_ALLOWED_TYPES", []string{
+			"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain",
+		}),
+		ThumbnailMaxDimension: getIntEnv("THUMBNAIL_MAX_DIMENSION", 320),
+
+		// Presence
+		PresenceTTL:       getIntEnv("PRESENCE_TTL", 30),
+		PresenceAwayAfter: getIntEnv("PRESENCE_AWAY_AFTER", 300),
+
+		// Idempotency
+		IdempotencyWindow: getIntEnv("IDEMPOTENCY_WINDOW", 86400),
+
+		// Ownership
+		OrphanedChatsOwner: getEnv("ORPHANED_CHATS_OWNER", ""),
 	}
 }
 
@@ -65,3 +132,23 @@ func getIntEnv(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getListEnv reads a comma-separated list, ignoring empty items.
+func getListEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		return defaultValue
+	}
+	return items
+}
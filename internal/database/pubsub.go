@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/models"
+)
+
+const (
+	eventsChannel = "chat_events"
+
+	notifyTimeout     = 5 * time.Second
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+	recoveryBatchSize = 500
+)
+
+// MessageLoader reads the messages referenced by notifications.
+type MessageLoader interface {
+	GetByID(ctx context.Context, id int) (*models.Message, error)
+	ListSince(ctx context.Context, chatID int, afterID int, limit int) ([]models.Message, error)
+}
+
+// PresenceSink receives the presence states published by other instances.
+type PresenceSink interface {
+	ApplyRemote(origin string, chatID int, state models.Presence)
+}
+
+// notification is the NOTIFY payload. It only references the message because
+// payloads are limited to 8000 bytes, which a message text may exceed.
+// Presence changes carry the instance they come from as Origin.
+type notification struct {
+	Type      string           `json:"type"`
+	ChatID    int              `json:"chat_id"`
+	MessageID int              `json:"message_id,omitempty"`
+	UserID    string           `json:"user_id,omitempty"`
+	Origin    string           `json:"origin,omitempty"`
+	Presence  *models.Presence `json:"presence,omitempty"`
+}
+
+// PubSub distributes chat events between service instances sharing the same
+// database. Publish issues a NOTIFY; Run listens on a dedicated connection and
+// hands every notification, including the instance's own, to the local broker.
+// Presence changes of other instances go to the presence sink instead, which
+// merges them with its own state.
+type PubSub struct {
+	database   *Database
+	broker     *events.Broker
+	messages   MessageLoader
+	presence   PresenceSink
+	instanceID string
+
+	// Only accessed by the Run goroutine. initialized is set once the
+	// starting position has been recorded on the first connection.
+	initialized   bool
+	lastMessageID int
+	recovered     map[int]struct{}
+}
+
+func (d *Database) NewPubSub(broker *events.Broker, messages MessageLoader) *PubSub {
+	return &PubSub{
+		database:   d,
+		broker:     broker,
+		messages:   messages,
+		instanceID: newInstanceID(),
+	}
+}
+
+// HandlePresence sets the sink of presence changes. It must be called before
+// Run.
+func (p *PubSub) HandlePresence(sink PresenceSink) {
+	p.presence = sink
+}
+
+func newInstanceID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (p *PubSub) Publish(event events.Event) {
+	payload := notification{
+		Type:   event.Type,
+		ChatID: event.ChatID,
+	}
+
+	switch data := event.Data.(type) {
+	case *models.Message:
+		payload.MessageID = data.ID
+	case events.MemberRemoved:
+		payload.UserID = data.UserID
+	case models.Presence:
+		payload.Origin = p.instanceID
+		payload.Presence = &data
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Failed to encode notification", "error", err, "event", event.Type)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	err = p.database.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", eventsChannel, string(data)).Error
+	if err != nil {
+		slog.Error("Failed to notify", "error", err, "event", event.Type, "chatID", event.ChatID)
+	}
+}
+
+// Run listens for notifications until ctx is cancelled, reconnecting with
+// exponential backoff whenever the listener connection is lost.
+func (p *PubSub) Run(ctx context.Context) {
+	delay := minReconnectDelay
+
+	for {
+		connected, err := p.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if connected {
+			delay = minReconnectDelay
+		}
+
+		slog.Error("Notification listener disconnected", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// listen serves a single listener connection. It reports whether the
+// connection was established before it failed.
+func (p *PubSub) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(ctx, getDSN(p.database.cfg))
+	if err != nil {
+		return false, fmt.Errorf("connect listener: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+		return false, fmt.Errorf("listen: %w", err)
+	}
+
+	// LISTEN is active before recovery starts, so nothing committed from now
+	// on can be missed; recovered messages are remembered to drop duplicates.
+	if err := p.recover(ctx); err != nil {
+		return false, fmt.Errorf("recover missed messages: %w", err)
+	}
+
+	slog.Info("Listening for chat notifications", "channel", eventsChannel)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		var payload notification
+		if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+			slog.Warn("Ignoring malformed notification", "error", err, "payload", n.Payload)
+			continue
+		}
+
+		p.dispatch(ctx, payload)
+	}
+}
+
+func (p *PubSub) dispatch(ctx context.Context, payload notification) {
+	event := events.Event{
+		Type:   payload.Type,
+		ChatID: payload.ChatID,
+	}
+
+	switch payload.Type {
+	case events.TypeChatDeleted:
+		event.Data = map[string]int{"chat_id": payload.ChatID}
+
+	case events.TypeMemberRemoved:
+		event.Data = events.MemberRemoved{ChatID: payload.ChatID, UserID: payload.UserID}
+
+	case events.TypePresenceChanged:
+		if payload.Origin != p.instanceID && payload.Presence != nil && p.presence != nil {
+			p.presence.ApplyRemote(payload.Origin, payload.ChatID, *payload.Presence)
+		}
+		return
+
+	case events.TypeMessageCreated, events.TypeMessageUpdated:
+		if payload.Type == events.TypeMessageCreated {
+			if _, ok := p.recovered[payload.MessageID]; ok {
+				return
+			}
+			event.ID = payload.MessageID
+			p.lastMessageID = max(p.lastMessageID, payload.MessageID)
+		}
+
+		if !p.broker.HasSubscribers(payload.ChatID) {
+			return
+		}
+
+		message, err := p.messages.GetByID(ctx, payload.MessageID)
+		if err != nil {
+			// The chat may have been deleted in the meantime.
+			slog.Warn("Failed to load notified message", "error", err, "messageID", payload.MessageID)
+			return
+		}
+		event.Data = message
+
+	default:
+		slog.Warn("Ignoring unknown notification", "type", payload.Type)
+		return
+	}
+
+	p.broker.Publish(event)
+}
+
+// recover replays the messages created while the listener was disconnected to
+// the chats that have local subscribers. On the first connection there is
+// nothing to recover and only the starting position is recorded.
+//
+// Messages are replayed by id, which is allocated before the inserting
+// transaction commits. A message whose transaction commits after one with a
+// higher id was seen is therefore skipped if its notification was lost with
+// the connection; clients resuming with Last-Event-ID have the same limitation.
+func (p *PubSub) recover(ctx context.Context) error {
+	p.recovered = make(map[int]struct{})
+
+	if !p.initialized {
+		err := p.database.db.WithContext(ctx).
+			Raw("SELECT COALESCE(MAX(id), 0) FROM messages").
+			Scan(&p.lastMessageID).Error
+		if err != nil {
+			return fmt.Errorf("get last message id: %w", err)
+		}
+		p.initialized = true
+		return nil
+	}
+
+	since := p.lastMessageID
+	for _, chatID := range p.broker.ChatIDs() {
+		afterID := since
+		for {
+			messages, err := p.messages.ListSince(ctx, chatID, afterID, recoveryBatchSize)
+			if err != nil {
+				return err
+			}
+
+			for i := range messages {
+				p.broker.Publish(events.Event{
+					ID:     messages[i].ID,
+					Type:   events.TypeMessageCreated,
+					ChatID: chatID,
+					Data:   &messages[i],
+				})
+
+				afterID = messages[i].ID
+				p.recovered[afterID] = struct{}{}
+				p.lastMessageID = max(p.lastMessageID, afterID)
+			}
+
+			if len(messages) < recoveryBatchSize {
+				break
+			}
+		}
+	}
+
+	if len(p.recovered) > 0 {
+		slog.Info("Recovered missed messages", "count", len(p.recovered), "since", since)
+	}
+
+	return nil
+}
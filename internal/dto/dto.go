@@ -1,9 +1,156 @@
 package dto
 
+import (
+	"io"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+)
+
 type CreateChatRequest struct {
 	Title string `json:"title"`
 }
 
+// CreateMessageRequest creates a root message, or a reply in the thread of
+// ParentID when it is set. AttachmentIDs reference files uploaded beforehand;
+// a message with attachments may have no text. A message with SendAt is
+// scheduled instead of being posted at once, and a message with ExpiresAt is
+// deleted at that time. ClientID is a UUID generated by the client; sending a
+// message with the same ClientID again returns the stored message.
 type CreateMessageRequest struct {
+	Text          string     `json:"text"`
+	ParentID      *int       `json:"parent_id"`
+	AttachmentIDs []int      `json:"attachment_ids"`
+	SendAt        *time.Time `json:"send_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ClientID      *string    `json:"client_id"`
+}
+
+// AttachmentUpload is a file received from a client. ContentType is detected
+// from the content rather than taken from the request.
+type AttachmentUpload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+}
+
+type UpdateMessageRequest struct {
 	Text string `json:"text"`
 }
+
+// PageRequest selects a window of chat messages. Before and After are opaque
+// cursors previously returned in PageCursors; at most one of them may be set.
+type PageRequest struct {
+	Limit  int
+	Before string
+	After  string
+}
+
+// PageCursors holds the tokens for fetching the adjacent pages. A cursor is
+// omitted when there is nothing more to fetch in that direction.
+type PageCursors struct {
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+type ChatResponse struct {
+	*models.Chat
+	Cursors     PageCursors `json:"cursors"`
+	UnreadCount int64       `json:"unread_count"`
+}
+
+type MessagePage struct {
+	Messages []models.Message `json:"messages"`
+	Cursors  PageCursors      `json:"cursors"`
+}
+
+// ThreadPage is a window over the replies to Root, newest first.
+type ThreadPage struct {
+	Root     *models.Message  `json:"root"`
+	Messages []models.Message `json:"messages"`
+	Cursors  PageCursors      `json:"cursors"`
+}
+
+const (
+	ChatSortCreatedAt    = "created_at"
+	ChatSortLastActivity = "last_activity"
+)
+
+type ListChatsRequest struct {
+	Limit       int
+	Offset      int
+	Sort        string
+	TitlePrefix string
+}
+
+type ChatList struct {
+	Chats []models.ChatSummary `json:"chats"`
+	Total int64                `json:"total"`
+}
+
+type MessageRevisions struct {
+	Revisions []models.MessageRevision `json:"revisions"`
+}
+
+type SearchRequest struct {
+	Query  string
+	ChatID int
+	Limit  int
+	Offset int
+}
+
+type SearchResult struct {
+	Hits  []models.SearchHit `json:"hits"`
+	Total int64              `json:"total"`
+}
+
+// AddMemberRequest adds a user to a chat. Role defaults to member.
+type AddMemberRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// UpdateMemberRequest changes the role of a member. Granting the owner role
+// transfers the ownership of the chat.
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateRetentionRequest sets the retention policy of a chat. MaxAge is in
+// seconds; omitted fields remove the corresponding limit.
+type UpdateRetentionRequest struct {
+	MaxAge      *int `json:"max_age"`
+	MaxMessages *int `json:"max_messages"`
+}
+
+type MemberList struct {
+	Members []models.ChatMember `json:"members"`
+}
+
+// CreateInviteRequest describes a new invite. Omitted fields leave the invite
+// unlimited in time or number of uses.
+type CreateInviteRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses"`
+}
+
+// InviteResponse is the only place the plain invite token is ever shown.
+type InviteResponse struct {
+	*models.ChatInvite
+	Token string `json:"token"`
+}
+
+type PresenceList struct {
+	Users []models.Presence `json:"users"`
+}
+
+type InviteList struct {
+	Invites []models.ChatInvite `json:"invites"`
+}
+
+// MarkReadRequest moves the read position of the caller. A zero MessageID
+// marks the whole chat as read.
+type MarkReadRequest struct {
+	MessageID int `json:"message_id"`
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"log/slog"
+	"sync"
+)
+
+const (
+	TypeMessageCreated  = "message.created"
+	TypeMessageUpdated  = "message.updated"
+	TypeChatDeleted     = "chat.deleted"
+	TypeMemberRemoved   = "member.removed"
+	TypePresenceChanged = "presence.changed"
+)
+
+// Event is a change in a chat delivered to live subscribers. ID is set only
+// for message.created events and holds the message id, which lets streaming
+// clients resume from the messages table.
+type Event struct {
+	ID     int    `json:"id,omitempty"`
+	Type   string `json:"type"`
+	ChatID int    `json:"chat_id"`
+	Data   any    `json:"data"`
+}
+
+// MemberRemoved is the data of member.removed events. The subscriptions of the
+// removed user to the chat are closed once the event has been delivered.
+type MemberRemoved struct {
+	ChatID int    `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Broker fans events out to the subscribers of a chat within this process.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[int]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[int]map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of a single chat on behalf of a user. Its
+// channel is closed when the subscription is cancelled, when the broker shuts
+// down, when the user is removed from the chat or when the subscriber falls
+// more than buffer events behind.
+type Subscription struct {
+	ChatID int
+	UserID string
+
+	broker *Broker
+	events chan Event
+	once   sync.Once
+}
+
+func (b *Broker) Subscribe(chatID int, userID string, buffer int) *Subscription {
+	sub := &Subscription{
+		ChatID: chatID,
+		UserID: userID,
+		broker: b,
+		events: make(chan Event, buffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		sub.closeChannel()
+		return sub
+	}
+
+	if b.subs[chatID] == nil {
+		b.subs[chatID] = make(map[*Subscription]struct{})
+	}
+	b.subs[chatID][sub] = struct{}{}
+
+	return sub
+}
+
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	removed, _ := event.Data.(MemberRemoved)
+
+	for sub := range b.subs[event.ChatID] {
+		select {
+		case sub.events <- event:
+		default:
+			slog.Warn("Dropping slow subscriber", "chatID", event.ChatID)
+			b.remove(sub)
+			continue
+		}
+
+		if event.Type == TypeMemberRemoved && sub.UserID == removed.UserID {
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *Broker) HasSubscribers(chatID int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs[chatID]) > 0
+}
+
+// ChatIDs returns the chats that currently have at least one subscriber.
+func (b *Broker) ChatIDs() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]int, 0, len(b.subs))
+	for chatID := range b.subs {
+		ids = append(ids, chatID)
+	}
+	return ids
+}
+
+// Close cancels every subscription and rejects new ones. It is intended to be
+// called once during shutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if subs, ok := b.subs[sub.ChatID]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(b.subs, sub.ChatID)
+		}
+	}
+	sub.closeChannel()
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.remove(s)
+}
+
+func (s *Subscription) closeChannel() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+}
@@ -0,0 +1,97 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_PublishToChatSubscribers(t *testing.T) {
+	// Arrange
+	broker := events.NewBroker()
+	first := broker.Subscribe(1, "alice", 1)
+	other := broker.Subscribe(2, "alice", 1)
+
+	// Act
+	broker.Publish(events.Event{Type: events.TypeMessageCreated, ChatID: 1})
+
+	// Assert
+	event := <-first.Events()
+	assert.Equal(t, 1, event.ChatID)
+	assert.Empty(t, other.Events())
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	// Arrange
+	broker := events.NewBroker()
+	sub := broker.Subscribe(1, "alice", 1)
+
+	// Act
+	broker.Publish(events.Event{ChatID: 1})
+	broker.Publish(events.Event{ChatID: 1})
+
+	// Assert
+	_, ok := <-sub.Events()
+	assert.True(t, ok)
+	_, ok = <-sub.Events()
+	assert.False(t, ok)
+}
+
+func TestBroker_MemberRemovedEndsTheirSubscriptions(t *testing.T) {
+	// Arrange
+	broker := events.NewBroker()
+	removed := broker.Subscribe(1, "alice", 2)
+	other := broker.Subscribe(1, "bob", 2)
+
+	// Act
+	broker.Publish(events.Event{
+		Type:   events.TypeMemberRemoved,
+		ChatID: 1,
+		Data:   events.MemberRemoved{ChatID: 1, UserID: "alice"},
+	})
+	broker.Publish(events.Event{Type: events.TypeMessageCreated, ChatID: 1})
+
+	// Assert
+	event, ok := <-removed.Events()
+	assert.True(t, ok)
+	assert.Equal(t, events.TypeMemberRemoved, event.Type)
+	_, ok = <-removed.Events()
+	assert.False(t, ok)
+
+	assert.Len(t, other.Events(), 2)
+}
+
+func TestBroker_CloseEndsSubscriptions(t *testing.T) {
+	// Arrange
+	broker := events.NewBroker()
+	sub := broker.Subscribe(1, "alice", 1)
+
+	// Act
+	broker.Close()
+	sub.Close()
+
+	// Assert
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+
+	late := broker.Subscribe(1, "alice", 1)
+	_, ok = <-late.Events()
+	assert.False(t, ok)
+}
+
+func TestBroker_TracksSubscribedChats(t *testing.T) {
+	// Arrange
+	broker := events.NewBroker()
+	sub := broker.Subscribe(7, "alice", 1)
+
+	// Act & Assert
+	assert.True(t, broker.HasSubscribers(7))
+	assert.False(t, broker.HasSubscribers(8))
+	assert.Equal(t, []int{7}, broker.ChatIDs())
+
+	sub.Close()
+
+	assert.False(t, broker.HasSubscribers(7))
+	assert.Empty(t, broker.ChatIDs())
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/jonx8/chat-service/internal/thumbnails"
+)
+
+const (
+	// multipartOverhead is allowed on top of the file size for the boundaries
+	// and headers of a multipart request.
+	multipartOverhead = 64 << 10
+
+	// multipartMemory is the part of an upload kept in memory; the rest is
+	// spooled to a temporary file.
+	multipartMemory = 1 << 20
+
+	// maxFileNameLength matches the size of attachments.file_name.
+	maxFileNameLength = 255
+
+	// sniffLength is the number of bytes http.DetectContentType considers.
+	sniffLength = 512
+)
+
+type AttachmentHandler struct {
+	attachmentService services.AttachmentService
+	maxSize           int64
+}
+
+// NewAttachmentHandler returns a handler rejecting uploads larger than maxSize
+// bytes before they reach the service.
+func NewAttachmentHandler(attachmentService services.AttachmentService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{attachmentService: attachmentService, maxSize: maxSize}
+}
+
+// UploadAttachment accepts a multipart form with the file in the "file" field.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "File is too large")
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "file field is required")
+		return
+	}
+	defer file.Close()
+
+	fileName := strings.TrimSpace(header.Filename)
+	if len(fileName) < 1 || len(fileName) > maxFileNameLength {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "File name length must be between 1 and 255")
+		return
+	}
+
+	if header.Size > h.maxSize {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "File is too large")
+		return
+	}
+
+	contentType, err := detectContentType(file)
+	if err != nil {
+		slog.Error("Failed to read upload", "error", err, "chatID", chatID)
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		return
+	}
+
+	upload := &dto.AttachmentUpload{
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        header.Size,
+		Content:     file,
+	}
+
+	attachment, err := h.attachmentService.UploadAttachment(r.Context(), chatID, upload)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to post in this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrAttachmentTooLarge):
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "File is too large")
+		case errors.Is(err, services.ErrAttachmentTypeInvalid):
+			writeJSONError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "File type "+contentType+" is not allowed")
+		default:
+			slog.Error("Failed to upload attachment", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(attachment); err != nil {
+		slog.Error("Failed to serialize attachment", "error", err, "attachment", attachment)
+	}
+}
+
+// DownloadAttachment serves the content of an attachment. Range and
+// conditional requests are handled by http.ServeContent.
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, h.attachmentService.OpenAttachment, func(attachment *models.Attachment) {
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	})
+}
+
+// DownloadThumbnail serves the thumbnail of an image attachment.
+func (h *AttachmentHandler) DownloadThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, h.attachmentService.OpenThumbnail, func(attachment *models.Attachment) {
+		w.Header().Set("Content-Type", thumbnails.ContentType)
+	})
+}
+
+type openFunc func(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error)
+
+// serve opens content with open and writes it, letting setHeaders describe it
+// first.
+func (h *AttachmentHandler) serve(w http.ResponseWriter, r *http.Request, open openFunc, setHeaders func(*models.Attachment)) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(r.PathValue("attachmentId"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Attachment ID path param must be integer")
+		return
+	}
+
+	attachment, content, err := open(r.Context(), chatID, attachmentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrAttachmentNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Attachment not found")
+		default:
+			slog.Error("Failed to open attachment", "error", err, "chatID", chatID, "attachmentID", attachmentID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+	defer content.Close()
+
+	setHeaders(attachment)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private")
+
+	http.ServeContent(w, r, "", attachment.CreatedAt, content)
+}
+
+// detectContentType sniffs the media type of an upload, without parameters,
+// and rewinds it.
+func detectContentType(file io.ReadSeeker) (string, error) {
+	buf := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	if err != nil {
+		return "application/octet-stream", nil
+	}
+	return mediaType, nil
+}
@@ -0,0 +1,254 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAttachmentService struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentService) UploadAttachment(ctx context.Context, chatID int, upload *dto.AttachmentUpload) (*models.Attachment, error) {
+	args := m.Called(ctx, chatID, upload)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentService) OpenAttachment(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error) {
+	args := m.Called(ctx, chatID, attachmentID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Attachment), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+func (m *MockAttachmentService) OpenThumbnail(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error) {
+	args := m.Called(ctx, chatID, attachmentID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Attachment), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+// pngHeader is enough for content sniffing to detect a PNG image.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func newUploadRequest(t *testing.T, fileName string, content []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("file", fileName)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/chats/1/attachments", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetPathValue("id", "1")
+	return req
+}
+
+func TestUploadAttachmentHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	expected := &models.Attachment{ID: 5, ChatID: 1, FileName: "logo.png", ContentType: "image/png", Size: int64(len(pngHeader))}
+	mockService.On("UploadAttachment", mock.Anything, 1, mock.MatchedBy(func(upload *dto.AttachmentUpload) bool {
+		return upload.FileName == "logo.png" && upload.ContentType == "image/png" && upload.Size == int64(len(pngHeader))
+	})).Return(expected, nil)
+
+	req := newUploadRequest(t, "logo.png", pngHeader)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UploadAttachment(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]any
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "logo.png", response["file_name"])
+	assert.Equal(t, "/chats/1/attachments/5", response["url"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUploadAttachmentHandler_TooLarge(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 8)
+
+	req := newUploadRequest(t, "logo.png", pngHeader)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UploadAttachment(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	mockService.AssertNotCalled(t, "UploadAttachment")
+}
+
+func TestUploadAttachmentHandler_MissingFile(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("text", "hello"))
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/chats/1/attachments", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UploadAttachment(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "UploadAttachment")
+}
+
+func TestUploadAttachmentHandler_TypeNotAllowed(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	mockService.On("UploadAttachment", mock.Anything, 1, mock.Anything).
+		Return(nil, services.ErrAttachmentTypeInvalid)
+
+	req := newUploadRequest(t, "page.html", []byte("<html><body>hi</body></html>"))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UploadAttachment(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadAttachmentHandler_Range(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	attachment := &models.Attachment{ID: 5, ChatID: 1, FileName: "notes.txt", ContentType: "text/plain", CreatedAt: time.Now()}
+	content := nopSeekCloser{strings.NewReader("0123456789")}
+	mockService.On("OpenAttachment", mock.Anything, 1, 5).Return(attachment, content, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/attachments/5", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("attachmentId", "5")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DownloadAttachment(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "2345", w.Body.String())
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename=notes.txt`)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadAttachmentHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	mockService.On("OpenAttachment", mock.Anything, 1, 5).Return(nil, nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("GET", "/chats/1/attachments/5", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("attachmentId", "5")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DownloadAttachment(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadThumbnailHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	attachment := &models.Attachment{ID: 5, ChatID: 1, FileName: "photo.jpg", ContentType: "image/jpeg", CreatedAt: time.Now()}
+	content := nopSeekCloser{bytes.NewReader(pngHeader)}
+	mockService.On("OpenThumbnail", mock.Anything, 1, 5).Return(attachment, content, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/attachments/5/thumbnail", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("attachmentId", "5")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DownloadThumbnail(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, pngHeader, w.Body.Bytes())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadThumbnailHandler_NotReady(t *testing.T) {
+	// Arrange
+	mockService := new(MockAttachmentService)
+	handler := handlers.NewAttachmentHandler(mockService, 1024)
+
+	mockService.On("OpenThumbnail", mock.Anything, 1, 5).Return(nil, nil, services.ErrAttachmentNotFound)
+
+	req := httptest.NewRequest("GET", "/chats/1/attachments/5/thumbnail", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("attachmentId", "5")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DownloadThumbnail(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
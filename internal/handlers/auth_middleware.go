@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/auth"
+)
+
+// TokenVerifier validates a bearer token and returns the caller identity.
+type TokenVerifier interface {
+	Verify(token string) (auth.Identity, error)
+}
+
+// AuthMiddleware rejects requests without a valid bearer token and stores the
+// caller identity in the request context.
+//
+// Browsers cannot set headers on WebSocket and EventSource requests, so for
+// those the token is also accepted in the access_token query param.
+func AuthMiddleware(verifier TokenVerifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chat-service"`)
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Missing bearer token")
+			return
+		}
+
+		identity, err := verifier.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chat-service", error="invalid_token"`)
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", false
+		}
+		return strings.TrimSpace(token), true
+	}
+
+	if isStreamingRequest(r) {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			return token, true
+		}
+	}
+
+	return "", false
+}
+
+func isStreamingRequest(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
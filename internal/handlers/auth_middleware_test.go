@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/auth"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenVerifier struct {
+	mock.Mock
+}
+
+func (m *MockTokenVerifier) Verify(token string) (auth.Identity, error) {
+	args := m.Called(token)
+	return args.Get(0).(auth.Identity), args.Error(1)
+}
+
+func identityEcho() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := auth.FromContext(r.Context())
+		_, _ = w.Write([]byte(identity.Subject))
+	})
+}
+
+func TestAuthMiddleware_ValidToken(t *testing.T) {
+	// Arrange
+	verifier := new(MockTokenVerifier)
+	verifier.On("Verify", "good").Return(auth.Identity{Subject: "alice"}, nil)
+
+	req := httptest.NewRequest("GET", "/chats", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.AuthMiddleware(verifier, identityEcho()).ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+	verifier.AssertExpectations(t)
+}
+
+func TestAuthMiddleware_MissingToken(t *testing.T) {
+	// Arrange
+	verifier := new(MockTokenVerifier)
+
+	req := httptest.NewRequest("GET", "/chats?access_token=good", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.AuthMiddleware(verifier, identityEcho()).ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "UNAUTHORIZED", response["error"])
+	verifier.AssertNotCalled(t, "Verify", mock.Anything)
+}
+
+func TestAuthMiddleware_InvalidToken(t *testing.T) {
+	// Arrange
+	verifier := new(MockTokenVerifier)
+	verifier.On("Verify", "bad").Return(auth.Identity{}, errors.New("expired"))
+
+	req := httptest.NewRequest("GET", "/chats", nil)
+	req.Header.Set("Authorization", "Bearer bad")
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.AuthMiddleware(verifier, identityEcho()).ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "UNAUTHORIZED", response["error"])
+	verifier.AssertExpectations(t)
+}
+
+func TestAuthMiddleware_QueryTokenForEventStream(t *testing.T) {
+	// Arrange
+	verifier := new(MockTokenVerifier)
+	verifier.On("Verify", "good").Return(auth.Identity{Subject: "bob"}, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/events?access_token=good", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.AuthMiddleware(verifier, identityEcho()).ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob", w.Body.String())
+	verifier.AssertExpectations(t)
+}
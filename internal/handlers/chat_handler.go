@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -30,15 +32,15 @@ func (h *ChatHandler) GetChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limit := 20
-	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
-		if val, err := strconv.Atoi(limitParam); err == nil && val >= 1 && val <= 100 {
-			limit = val
-		}
-	}
-	chat, err := h.chatService.GetChat(r.Context(), chatID, limit)
+	chat, err := h.chatService.GetChat(r.Context(), chatID, parsePageRequest(r))
 	if err != nil {
 		switch {
+		case errors.Is(err, services.ErrInvalidCursor):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid pagination cursor")
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
 		case errors.Is(err, services.ErrChatNotFound):
 			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
 		default:
@@ -73,6 +75,8 @@ func (h *ChatHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
 	chat, err := h.chatService.CreateChat(r.Context(), &request)
 	if err != nil {
 		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
 		case errors.Is(err, services.ErrChatAlreadyExists):
 			message := fmt.Sprintf("Chat with title %s already exists", request.Title)
 			writeJSONError(w, http.StatusConflict, "CONFLICT", message)
@@ -90,6 +94,42 @@ func (h *ChatHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// OpenDirectChat replies with 201 when the direct chat has just been created
+// and with 200 when it already existed.
+func (h *ChatHandler) OpenDirectChat(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	if len(userID) < 1 || len(userID) > maxUserIDLength {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "User ID length must be between 1 and 255")
+		return
+	}
+
+	chat, created, err := h.chatService.OpenDirectChat(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrDirectChatToSelf):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Cannot open a direct chat with yourself")
+		default:
+			slog.Error("Failed to open direct chat", "error", err, "userID", userID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(chat); err != nil {
+		slog.Error("Failed to serialize chat", "error", err, "chat", chat)
+	}
+}
+
 func (h *ChatHandler) DeleteChat(w http.ResponseWriter, r *http.Request) {
 	chatID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
@@ -99,6 +139,10 @@ func (h *ChatHandler) DeleteChat(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.chatService.DeleteChat(r.Context(), chatID); err != nil {
 		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Only the owner may delete this chat")
 		case errors.Is(err, services.ErrChatNotFound):
 			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
 		default:
@@ -111,3 +155,136 @@ func (h *ChatHandler) DeleteChat(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 
 }
+
+// MarkRead accepts an empty body to mark the whole chat as read.
+func (h *ChatHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	var request dto.MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid json")
+		return
+	}
+
+	if request.MessageID < 0 {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "message_id must be a positive integer")
+		return
+	}
+
+	member, err := h.chatService.MarkRead(r.Context(), chatID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		default:
+			slog.Error("Failed to mark chat as read", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		slog.Error("Failed to serialize member", "error", err, "member", member)
+	}
+}
+
+func (h *ChatHandler) ListChats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	request := dto.ListChatsRequest{
+		Limit:       parseLimit(query),
+		Sort:        dto.ChatSortLastActivity,
+		TitlePrefix: strings.TrimSpace(query.Get("title")),
+	}
+
+	offset, ok := parseOffset(query)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Offset must be a non-negative integer")
+		return
+	}
+	request.Offset = offset
+
+	if sort := query.Get("sort"); sort != "" {
+		if sort != dto.ChatSortCreatedAt && sort != dto.ChatSortLastActivity {
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Sort must be one of created_at, last_activity")
+			return
+		}
+		request.Sort = sort
+	}
+
+	chats, err := h.chatService.ListChats(r.Context(), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		default:
+			slog.Error("Failed to list chats", "error", err)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(chats); err != nil {
+		slog.Error("Failed to serialize chats", "error", err)
+	}
+}
+
+func (h *ChatHandler) UpdateRetention(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	var request dto.UpdateRetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid json")
+		return
+	}
+
+	if request.MaxAge != nil && (*request.MaxAge < 1 || *request.MaxAge > math.MaxInt32) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "max_age must be a positive number of seconds")
+		return
+	}
+
+	if request.MaxMessages != nil && (*request.MaxMessages < 1 || *request.MaxMessages > math.MaxInt32) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "max_messages must be a positive integer")
+		return
+	}
+
+	retention, err := h.chatService.UpdateRetention(r.Context(), chatID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Only the owner can change the retention policy")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to update retention", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(retention); err != nil {
+		slog.Error("Failed to serialize retention", "error", err, "chatID", chatID)
+	}
+}
@@ -30,12 +30,20 @@ func (m *MockChatService) CreateChat(ctx context.Context, req *dto.CreateChatReq
 	return args.Get(0).(*models.Chat), args.Error(1)
 }
 
-func (m *MockChatService) GetChat(ctx context.Context, chatID int, limit int) (*models.Chat, error) {
-	args := m.Called(ctx, chatID, limit)
+func (m *MockChatService) OpenDirectChat(ctx context.Context, userID string) (*models.Chat, bool, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*models.Chat), args.Bool(1), args.Error(2)
+}
+
+func (m *MockChatService) GetChat(ctx context.Context, chatID int, req *dto.PageRequest) (*dto.ChatResponse, error) {
+	args := m.Called(ctx, chatID, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.Chat), args.Error(1)
+	return args.Get(0).(*dto.ChatResponse), args.Error(1)
 }
 
 func (m *MockChatService) DeleteChat(ctx context.Context, chatID int) error {
@@ -43,6 +51,35 @@ func (m *MockChatService) DeleteChat(ctx context.Context, chatID int) error {
 	return args.Error(0)
 }
 
+func (m *MockChatService) CheckAccess(ctx context.Context, chatID int) error {
+	args := m.Called(ctx, chatID)
+	return args.Error(0)
+}
+
+func (m *MockChatService) MarkRead(ctx context.Context, chatID int, req *dto.MarkReadRequest) (*models.ChatMember, error) {
+	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatMember), args.Error(1)
+}
+
+func (m *MockChatService) UpdateRetention(ctx context.Context, chatID int, req *dto.UpdateRetentionRequest) (*models.Retention, error) {
+	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Retention), args.Error(1)
+}
+
+func (m *MockChatService) ListChats(ctx context.Context, req *dto.ListChatsRequest) (*dto.ChatList, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ChatList), args.Error(1)
+}
+
 func TestCreateChatHandler_Success(t *testing.T) {
 	// Arrange
 	mockService := new(MockChatService)
@@ -138,8 +175,8 @@ func TestGetChatHandler_Success(t *testing.T) {
 		},
 	}
 
-	mockService.On("GetChat", mock.Anything, 1, 20).
-		Return(expectedChat, nil)
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 20}).
+		Return(&dto.ChatResponse{Chat: expectedChat}, nil)
 
 	req := httptest.NewRequest("GET", "/chats/1", nil)
 	req.SetPathValue("id", "1")
@@ -165,7 +202,7 @@ func TestGetChatHandler_NotFound(t *testing.T) {
 	mockService := new(MockChatService)
 	handler := handlers.NewChatHandler(mockService)
 
-	mockService.On("GetChat", mock.Anything, 999, 20).
+	mockService.On("GetChat", mock.Anything, 999, &dto.PageRequest{Limit: 20}).
 		Return(nil, services.ErrChatNotFound)
 
 	req := httptest.NewRequest("GET", "/chats/999", nil)
@@ -215,8 +252,8 @@ func TestGetChatHandler_WithLimit(t *testing.T) {
 
 	expectedChat := &models.Chat{ID: 1, Title: "Test"}
 
-	mockService.On("GetChat", mock.Anything, 1, 5).
-		Return(expectedChat, nil)
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 5}).
+		Return(&dto.ChatResponse{Chat: expectedChat}, nil)
 
 	req := httptest.NewRequest("GET", "/chats/1?limit=5", nil)
 	req.SetPathValue("id", "1")
@@ -308,13 +345,466 @@ func TestGetChatHandler_LimitOutOfRange(t *testing.T) {
 	req.SetPathValue("id", "1")
 	w := httptest.NewRecorder()
 
-	mockService.On("GetChat", mock.Anything, 1, 20).
-		Return(&models.Chat{ID: 1}, nil)
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 20}).
+		Return(&dto.ChatResponse{Chat: &models.Chat{ID: 1}}, nil)
+
+	// Act
+	handler.GetChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetChatHandler_WithCursor(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expected := &dto.ChatResponse{
+		Chat:    &models.Chat{ID: 1, Title: "Test"},
+		Cursors: dto.PageCursors{Before: "older", After: "newer"},
+	}
+
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 10, Before: "abc"}).
+		Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1?limit=10&before=abc", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.ChatResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.ID)
+	assert.Equal(t, "older", response.Cursors.Before)
+	assert.Equal(t, "newer", response.Cursors.After)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetChatHandler_InvalidCursor(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 20, After: "garbage"}).
+		Return(nil, services.ErrInvalidCursor)
+
+	req := httptest.NewRequest("GET", "/chats/1?after=garbage", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "BAD_REQUEST", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListChatsHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expectedList := &dto.ChatList{
+		Chats: []models.ChatSummary{
+			{
+				ID:           1,
+				Title:        "Team",
+				MessageCount: 3,
+				LastMessage:  &models.MessagePreview{ID: 7, Text: "Hi"},
+			},
+		},
+		Total: 1,
+	}
+
+	expectedRequest := &dto.ListChatsRequest{
+		Limit:       10,
+		Offset:      20,
+		Sort:        dto.ChatSortCreatedAt,
+		TitlePrefix: "Te",
+	}
+
+	mockService.On("ListChats", mock.Anything, expectedRequest).
+		Return(expectedList, nil)
+
+	req := httptest.NewRequest("GET", "/chats?limit=10&offset=20&sort=created_at&title=Te", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListChats(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.ChatList
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.Total)
+	assert.Len(t, response.Chats, 1)
+	assert.Equal(t, int64(3), response.Chats[0].MessageCount)
+	assert.Equal(t, "Hi", response.Chats[0].LastMessage.Text)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListChatsHandler_Defaults(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expectedRequest := &dto.ListChatsRequest{Limit: 20, Sort: dto.ChatSortLastActivity}
+
+	mockService.On("ListChats", mock.Anything, expectedRequest).
+		Return(&dto.ChatList{Chats: []models.ChatSummary{}}, nil)
+
+	req := httptest.NewRequest("GET", "/chats", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListChats(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListChatsHandler_InvalidSort(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/chats?sort=title", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListChats(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "BAD_REQUEST", response["error"])
+}
+
+func TestGetChatHandler_IncludesAuthor(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	authorID := "alice"
+	expectedChat := &models.Chat{
+		ID:    1,
+		Title: "Test Chat",
+		Messages: []models.Message{
+			{ID: 1, ChatID: 1, Text: "Hello", AuthorID: &authorID, Author: &models.User{ID: authorID, Name: "Alice"}},
+		},
+	}
+
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 20}).
+		Return(&dto.ChatResponse{Chat: expectedChat}, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
 
 	// Act
 	handler.GetChat(w, req)
 
 	// Assert
 	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Messages []struct {
+			Author map[string]interface{} `json:"author"`
+		} `json:"messages"`
+	}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Messages, 1)
+	assert.Equal(t, map[string]interface{}{"id": "alice", "name": "Alice"}, response.Messages[0].Author)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateChatHandler_Unauthenticated(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	mockService.On("CreateChat", mock.Anything, mock.Anything).
+		Return(nil, services.ErrUnauthenticated)
+
+	req := httptest.NewRequest("POST", "/chats", bytes.NewBufferString(`{"title": "Chat"}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "UNAUTHORIZED", response["error"])
+}
+
+func TestGetChatHandler_NotAMember(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	mockService.On("GetChat", mock.Anything, 1, &dto.PageRequest{Limit: 20}).
+		Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("GET", "/chats/1", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "FORBIDDEN", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestOpenDirectChatHandler_Created(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expected := &models.Chat{ID: 5, Kind: models.ChatKindDirect, Messages: []models.Message{}}
+	mockService.On("OpenDirectChat", mock.Anything, "bob").Return(expected, true, nil)
+
+	req := httptest.NewRequest("PUT", "/dm/bob", nil)
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.OpenDirectChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.Chat
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, response.ID)
+	assert.Equal(t, models.ChatKindDirect, response.Kind)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestOpenDirectChatHandler_Existing(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expected := &models.Chat{ID: 5, Kind: models.ChatKindDirect, Messages: []models.Message{}}
+	mockService.On("OpenDirectChat", mock.Anything, "bob").Return(expected, false, nil)
+
+	req := httptest.NewRequest("PUT", "/dm/bob", nil)
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.OpenDirectChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestOpenDirectChatHandler_WithSelf(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	mockService.On("OpenDirectChat", mock.Anything, "alice").Return(nil, false, services.ErrDirectChatToSelf)
+
+	req := httptest.NewRequest("PUT", "/dm/alice", nil)
+	req.SetPathValue("userId", "alice")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.OpenDirectChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "BAD_REQUEST", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestMarkReadHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	lastRead := 42
+	expected := &models.ChatMember{ChatID: 1, UserID: "alice", LastReadMessageID: &lastRead}
+	mockService.On("MarkRead", mock.Anything, 1, &dto.MarkReadRequest{MessageID: 42}).Return(expected, nil)
+
+	req := httptest.NewRequest("POST", "/chats/1/read", bytes.NewBufferString(`{"message_id": 42}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.MarkRead(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.ChatMember
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, &lastRead, response.LastReadMessageID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestMarkReadHandler_EmptyBody(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	expected := &models.ChatMember{ChatID: 1, UserID: "alice"}
+	mockService.On("MarkRead", mock.Anything, 1, &dto.MarkReadRequest{}).Return(expected, nil)
+
+	req := httptest.NewRequest("POST", "/chats/1/read", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.MarkRead(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestMarkReadHandler_MessageNotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	mockService.On("MarkRead", mock.Anything, 1, &dto.MarkReadRequest{MessageID: 999}).
+		Return(nil, services.ErrMessageNotFound)
+
+	req := httptest.NewRequest("POST", "/chats/1/read", bytes.NewBufferString(`{"message_id": 999}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.MarkRead(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Message not found", response["message"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateRetentionHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	maxMessages := 100
+	expected := &models.Retention{MaxMessages: &maxMessages}
+	mockService.On("UpdateRetention", mock.Anything, 1, &dto.UpdateRetentionRequest{MaxMessages: &maxMessages}).
+		Return(expected, nil)
+
+	req := httptest.NewRequest("PUT", "/chats/1/retention", bytes.NewBufferString(`{"max_messages": 100}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateRetention(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.Retention
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Nil(t, response.MaxAge)
+	assert.Equal(t, &maxMessages, response.MaxMessages)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateRetentionHandler_InvalidMaxAge(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	req := httptest.NewRequest("PUT", "/chats/1/retention", bytes.NewBufferString(`{"max_age": 0}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateRetention(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "UpdateRetention")
+}
+
+func TestUpdateRetentionHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	handler := handlers.NewChatHandler(mockService)
+
+	mockService.On("UpdateRetention", mock.Anything, 1, &dto.UpdateRetentionRequest{}).Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("PUT", "/chats/1/retention", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateRetention(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
 	mockService.AssertExpectations(t)
 }
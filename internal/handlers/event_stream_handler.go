@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/auth"
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+const (
+	// heartbeatPeriod keeps idle streams alive through proxies that drop
+	// silent connections.
+	heartbeatPeriod = 15 * time.Second
+
+	// resumeBatchSize is the number of stored messages replayed per query
+	// when a client resumes with Last-Event-ID.
+	resumeBatchSize = 100
+)
+
+type EventStreamHandler struct {
+	chatService     services.ChatService
+	messageService  services.MessageService
+	presenceService services.PresenceService
+	broker          *events.Broker
+}
+
+func NewEventStreamHandler(chatService services.ChatService, messageService services.MessageService, presenceService services.PresenceService, broker *events.Broker) *EventStreamHandler {
+	return &EventStreamHandler{
+		chatService:     chatService,
+		messageService:  messageService,
+		presenceService: presenceService,
+		broker:          broker,
+	}
+}
+
+func (h *EventStreamHandler) StreamChat(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	lastEventID := 0
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		if lastEventID, err = strconv.Atoi(header); err != nil || lastEventID < 0 {
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Last-Event-ID must be a message id")
+			return
+		}
+	}
+
+	if err := h.chatService.CheckAccess(r.Context(), chatID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to check chat", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	leave, err := h.presenceService.Connect(r.Context(), chatID)
+	if err != nil {
+		slog.Error("Failed to track presence", "error", err, "chatID", chatID)
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		return
+	}
+	defer leave()
+
+	// Subscribe before replaying so that messages created during the replay
+	// are not lost; duplicates are filtered by id below.
+	identity, _ := auth.FromContext(r.Context())
+	sub := h.broker.Subscribe(chatID, identity.Subject, sendBufferSize)
+	defer sub.Close()
+
+	stream := newEventStream(w)
+	if err := stream.start(); err != nil {
+		slog.Warn("Failed to start event stream", "error", err, "chatID", chatID)
+		return
+	}
+
+	if lastEventID > 0 {
+		if lastEventID, err = h.replay(r, stream, chatID, lastEventID); err != nil {
+			slog.Warn("Failed to replay missed messages", "error", err, "chatID", chatID)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatPeriod)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+
+			if event.Type == events.TypeMessageCreated && event.ID <= lastEventID {
+				continue
+			}
+
+			if err := stream.send(event); err != nil {
+				return
+			}
+
+			if event.Type == events.TypeChatDeleted {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := stream.comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// replay sends the messages created after lastEventID and returns the id of
+// the last message sent.
+func (h *EventStreamHandler) replay(r *http.Request, stream *eventStream, chatID int, lastEventID int) (int, error) {
+	for {
+		messages, err := h.messageService.ListMessagesSince(r.Context(), chatID, lastEventID, resumeBatchSize)
+		if err != nil {
+			return lastEventID, err
+		}
+
+		for i := range messages {
+			event := events.Event{
+				ID:     messages[i].ID,
+				Type:   events.TypeMessageCreated,
+				ChatID: chatID,
+				Data:   &messages[i],
+			}
+
+			if err := stream.send(event); err != nil {
+				return lastEventID, err
+			}
+
+			lastEventID = messages[i].ID
+		}
+
+		if len(messages) < resumeBatchSize {
+			return lastEventID, nil
+		}
+	}
+}
+
+// eventStream writes text/event-stream frames. The server-wide WriteTimeout
+// would cut long-lived streams, so every frame gets its own write deadline.
+type eventStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	return &eventStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+	}
+}
+
+func (s *eventStream) start() error {
+	header := s.w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+
+	if err := s.extendDeadline(); err != nil {
+		return err
+	}
+
+	s.w.WriteHeader(http.StatusOK)
+
+	return s.controller.Flush()
+}
+
+func (s *eventStream) send(event events.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	if err := s.extendDeadline(); err != nil {
+		return err
+	}
+
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(s.w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+
+	return s.controller.Flush()
+}
+
+func (s *eventStream) comment(text string) error {
+	if err := s.extendDeadline(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+
+	return s.controller.Flush()
+}
+
+func (s *eventStream) extendDeadline() error {
+	err := s.controller.SetWriteDeadline(time.Now().Add(writeWait))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newEventStreamServer(t *testing.T, chatService *MockChatService, messageService *MockMessageService, broker *events.Broker) *httptest.Server {
+	t.Helper()
+
+	handler := handlers.NewEventStreamHandler(chatService, messageService, newConnectingPresenceService(), broker)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /chats/{id}/events", handler.StreamChat)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// readEventLines collects stream lines until n events have been received.
+func readEventLines(t *testing.T, reader *bufio.Reader, n int) []string {
+	t.Helper()
+
+	var lines []string
+	for n > 0 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			n--
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func TestEventStreamHandler_ResumesAndStreams(t *testing.T) {
+	// Arrange
+	chatService := new(MockChatService)
+	messageService := new(MockMessageService)
+	broker := events.NewBroker()
+	server := newEventStreamServer(t, chatService, messageService, broker)
+
+	chatService.On("CheckAccess", mock.Anything, 1).Return(nil)
+	messageService.On("ListMessagesSince", mock.Anything, 1, 10, mock.Anything).
+		Return([]models.Message{{ID: 11, ChatID: 1, Text: "missed"}}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/chats/1/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "10")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	replayed := readEventLines(t, reader, 1)
+
+	broker.Publish(events.Event{ID: 11, Type: events.TypeMessageCreated, ChatID: 1, Data: "duplicate"})
+	broker.Publish(events.Event{Type: events.TypeMessageUpdated, ChatID: 1, Data: map[string]int{"id": 11}})
+	broker.Publish(events.Event{Type: events.TypeChatDeleted, ChatID: 1, Data: map[string]int{"chat_id": 1}})
+
+	live := readEventLines(t, reader, 2)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	assert.Equal(t, "id: 11", replayed[0])
+	assert.Equal(t, "event: message.created", replayed[1])
+	assert.Contains(t, replayed[2], `"text":"missed"`)
+
+	assert.Equal(t, []string{
+		"event: message.updated",
+		`data: {"id":11}`,
+		"event: chat.deleted",
+		`data: {"chat_id":1}`,
+	}, live)
+
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err, "stream must end after chat.deleted")
+
+	chatService.AssertExpectations(t)
+	messageService.AssertExpectations(t)
+}
+
+func TestEventStreamHandler_InvalidLastEventID(t *testing.T) {
+	// Arrange
+	chatService := new(MockChatService)
+	messageService := new(MockMessageService)
+	handler := handlers.NewEventStreamHandler(chatService, messageService, newConnectingPresenceService(), events.NewBroker())
+
+	req := httptest.NewRequest("GET", "/chats/1/events", nil)
+	req.SetPathValue("id", "1")
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.StreamChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEventStreamHandler_ChatNotFound(t *testing.T) {
+	// Arrange
+	chatService := new(MockChatService)
+	messageService := new(MockMessageService)
+	handler := handlers.NewEventStreamHandler(chatService, messageService, newConnectingPresenceService(), events.NewBroker())
+
+	chatService.On("CheckAccess", mock.Anything, 999).Return(services.ErrChatNotFound)
+
+	req := httptest.NewRequest("GET", "/chats/999/events", nil)
+	req.SetPathValue("id", "999")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.StreamChat(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	chatService.AssertExpectations(t)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+const (
+	// maxIdempotencyKeyLength matches the size of idempotency_keys.key.
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodySize bounds the request bodies read to compute
+	// fingerprints.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// IdempotencyMiddleware makes requests carrying an Idempotency-Key header safe
+// to retry. The first request with a key is executed and its response is
+// stored; retries with the same key and body get the stored response, marked
+// with an Idempotent-Replayed header, and reusing a key for another request is
+// rejected. Responses with a 5xx status are not stored, so that such requests
+// can be retried. Requests without the header are passed through.
+//
+// It must run after AuthMiddleware, since keys belong to the caller.
+func IdempotencyMiddleware(idempotencyService services.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				writeJSONError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "Request body is too large")
+				return
+			}
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := idempotencyService.Begin(r.Context(), key, requestFingerprint(r, body))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrUnauthenticated):
+				writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+			case errors.Is(err, services.ErrIdempotencyKeyReused):
+				writeJSONError(w, http.StatusUnprocessableEntity, "UNPROCESSABLE_ENTITY", "Idempotency-Key was already used for a different request")
+			case errors.Is(err, services.ErrIdempotencyKeyInUse):
+				writeJSONError(w, http.StatusConflict, "CONFLICT", "A request with this Idempotency-Key is in progress")
+			default:
+				slog.Error("Failed to begin idempotent request", "error", err)
+				writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+			}
+			return
+		}
+
+		if record.StatusCode != nil {
+			replayResponse(w, record)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		// The outcome must be recorded even if the client has gone away.
+		ctx := context.WithoutCancel(r.Context())
+		status := recorder.statusCode()
+
+		if status >= http.StatusInternalServerError {
+			if err := idempotencyService.Release(ctx, record); err != nil {
+				slog.Error("Failed to release idempotency key", "error", err)
+			}
+			return
+		}
+
+		contentType := recorder.Header().Get("Content-Type")
+		record.StatusCode = &status
+		record.ContentType = &contentType
+		record.Body = recorder.body.Bytes()
+
+		if err := idempotencyService.Complete(ctx, record); err != nil {
+			slog.Error("Failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replayResponse(w http.ResponseWriter, record *models.IdempotencyKey) {
+	if record.ContentType != nil && *record.ContentType != "" {
+		w.Header().Set("Content-Type", *record.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(*record.StatusCode)
+	if _, err := w.Write(record.Body); err != nil {
+		slog.Warn("Failed to replay idempotent response", "error", err)
+	}
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
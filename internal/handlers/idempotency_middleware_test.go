@@ -0,0 +1,164 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyService struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyService) Begin(ctx context.Context, key string, fingerprint string) (*models.IdempotencyKey, error) {
+	args := m.Called(ctx, key, fingerprint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IdempotencyKey), args.Error(1)
+}
+
+func (m *MockIdempotencyService) Complete(ctx context.Context, key *models.IdempotencyKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) Release(ctx context.Context, key *models.IdempotencyKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+// countingHandler answers with status and counts its calls.
+func countingHandler(status int, calls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}
+}
+
+func newIdempotentRequest(key string, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/chats/1/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
+func TestIdempotencyMiddleware_StoresResponse(t *testing.T) {
+	// Arrange
+	mockService := new(MockIdempotencyService)
+	calls := 0
+
+	record := &models.IdempotencyKey{UserID: "alice", Key: "k1"}
+	mockService.On("Begin", mock.Anything, "k1", mock.Anything).Return(record, nil)
+	mockService.On("Complete", mock.Anything, mock.MatchedBy(func(key *models.IdempotencyKey) bool {
+		return *key.StatusCode == http.StatusCreated && *key.ContentType == "application/json" && string(key.Body) == `{"id":1}`
+	})).Return(nil)
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.IdempotencyMiddleware(mockService, countingHandler(http.StatusCreated, &calls)).
+		ServeHTTP(w, newIdempotentRequest("k1", `{"text":"hi"}`))
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, calls)
+	mockService.AssertExpectations(t)
+}
+
+func TestIdempotencyMiddleware_ReplaysResponse(t *testing.T) {
+	// Arrange
+	mockService := new(MockIdempotencyService)
+	calls := 0
+
+	status := http.StatusCreated
+	contentType := "application/json"
+	stored := &models.IdempotencyKey{UserID: "alice", Key: "k1", StatusCode: &status, ContentType: &contentType, Body: []byte(`{"id":7}`)}
+	mockService.On("Begin", mock.Anything, "k1", mock.Anything).Return(stored, nil)
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.IdempotencyMiddleware(mockService, countingHandler(http.StatusCreated, &calls)).
+		ServeHTTP(w, newIdempotentRequest("k1", `{"text":"hi"}`))
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"id":7}`, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 0, calls)
+	mockService.AssertExpectations(t)
+}
+
+func TestIdempotencyMiddleware_FingerprintDependsOnBody(t *testing.T) {
+	// Arrange
+	mockService := new(MockIdempotencyService)
+	calls := 0
+
+	var fingerprints []string
+	mockService.On("Begin", mock.Anything, "k1", mock.Anything).
+		Run(func(args mock.Arguments) { fingerprints = append(fingerprints, args.String(2)) }).
+		Return(nil, services.ErrIdempotencyKeyReused)
+
+	handler := handlers.IdempotencyMiddleware(mockService, countingHandler(http.StatusCreated, &calls))
+
+	// Act
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, newIdempotentRequest("k1", `{"text":"hi"}`))
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, newIdempotentRequest("k1", `{"text":"bye"}`))
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	assert.Len(t, fingerprints, 2)
+	assert.NotEqual(t, fingerprints[0], fingerprints[1])
+	assert.Equal(t, 0, calls)
+}
+
+func TestIdempotencyMiddleware_ReleasesOnServerError(t *testing.T) {
+	// Arrange
+	mockService := new(MockIdempotencyService)
+	calls := 0
+
+	record := &models.IdempotencyKey{UserID: "alice", Key: "k1"}
+	mockService.On("Begin", mock.Anything, "k1", mock.Anything).Return(record, nil)
+	mockService.On("Release", mock.Anything, record).Return(nil)
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.IdempotencyMiddleware(mockService, countingHandler(http.StatusInternalServerError, &calls)).
+		ServeHTTP(w, newIdempotentRequest("k1", `{"text":"hi"}`))
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+}
+
+func TestIdempotencyMiddleware_WithoutKey(t *testing.T) {
+	// Arrange
+	mockService := new(MockIdempotencyService)
+	calls := 0
+
+	req := httptest.NewRequest("POST", "/chats", bytes.NewBufferString(`{"title":"x"}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	handlers.IdempotencyMiddleware(mockService, countingHandler(http.StatusCreated, &calls)).ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, calls)
+	mockService.AssertNotCalled(t, "Begin", mock.Anything, mock.Anything, mock.Anything)
+}
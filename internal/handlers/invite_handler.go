@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+type InviteHandler struct {
+	inviteService services.InviteService
+}
+
+func NewInviteHandler(inviteService services.InviteService) *InviteHandler {
+	return &InviteHandler{inviteService: inviteService}
+}
+
+func (h *InviteHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	var request dto.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid json")
+		return
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "expires_at must be in the future")
+		return
+	}
+
+	if request.MaxUses != nil && *request.MaxUses < 1 {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "max_uses must be a positive integer")
+		return
+	}
+
+	invite, err := h.inviteService.CreateInvite(r.Context(), chatID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to invite members to this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to create invite", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(invite); err != nil {
+		slog.Error("Failed to serialize invite", "error", err, "inviteID", invite.ID)
+	}
+}
+
+func (h *InviteHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	invites, err := h.inviteService.ListInvites(r.Context(), chatID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to view invites of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to list invites", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dto.InviteList{Invites: invites}); err != nil {
+		slog.Error("Failed to serialize invites", "error", err, "chatID", chatID)
+	}
+}
+
+func (h *InviteHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	inviteID, err := strconv.Atoi(r.PathValue("inviteId"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invite ID path param must be integer")
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(r.Context(), chatID, inviteID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to revoke invites of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrInviteNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Invite not found")
+		default:
+			slog.Error("Failed to revoke invite", "error", err, "chatID", chatID, "inviteID", inviteID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	member, err := h.inviteService.AcceptInvite(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrInviteNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Invite not found")
+		case errors.Is(err, services.ErrInviteInvalid):
+			writeJSONError(w, http.StatusGone, "GONE", "Invite has expired or been revoked")
+		case errors.Is(err, services.ErrMemberAlreadyExists):
+			writeJSONError(w, http.StatusConflict, "CONFLICT", "You are already a member of this chat")
+		default:
+			slog.Error("Failed to accept invite", "error", err)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		slog.Error("Failed to serialize member", "error", err, "member", member)
+	}
+}
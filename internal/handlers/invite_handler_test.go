@@ -0,0 +1,201 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInviteService struct {
+	mock.Mock
+}
+
+func (m *MockInviteService) CreateInvite(ctx context.Context, chatID int, req *dto.CreateInviteRequest) (*dto.InviteResponse, error) {
+	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.InviteResponse), args.Error(1)
+}
+
+func (m *MockInviteService) ListInvites(ctx context.Context, chatID int) ([]models.ChatInvite, error) {
+	args := m.Called(ctx, chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChatInvite), args.Error(1)
+}
+
+func (m *MockInviteService) RevokeInvite(ctx context.Context, chatID int, inviteID int) error {
+	args := m.Called(ctx, chatID, inviteID)
+	return args.Error(0)
+}
+
+func (m *MockInviteService) AcceptInvite(ctx context.Context, token string) (*models.ChatMember, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatMember), args.Error(1)
+}
+
+func TestCreateInviteHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockInviteService)
+	handler := handlers.NewInviteHandler(mockService)
+
+	maxUses := 5
+	expected := &dto.InviteResponse{
+		ChatInvite: &models.ChatInvite{ID: 7, ChatID: 1, MaxUses: &maxUses},
+		Token:      "secret-token",
+	}
+	mockService.On("CreateInvite", mock.Anything, 1, &dto.CreateInviteRequest{MaxUses: &maxUses}).
+		Return(expected, nil)
+
+	req := httptest.NewRequest("POST", "/chats/1/invites", bytes.NewBufferString(`{"max_uses": 5}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateInvite(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-token", response["token"])
+	assert.Equal(t, float64(7), response["id"])
+	assert.NotContains(t, response, "token_hash")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateInviteHandler_ExpiresInPast(t *testing.T) {
+	// Arrange
+	mockService := new(MockInviteService)
+	handler := handlers.NewInviteHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/chats/1/invites", bytes.NewBufferString(`{"expires_at": "2020-01-01T00:00:00Z"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateInvite(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "CreateInvite")
+}
+
+func TestCreateInviteHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockInviteService)
+	handler := handlers.NewInviteHandler(mockService)
+
+	mockService.On("CreateInvite", mock.Anything, 1, mock.Anything).Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("POST", "/chats/1/invites", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateInvite(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestRevokeInviteHandler_NotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockInviteService)
+	handler := handlers.NewInviteHandler(mockService)
+
+	mockService.On("RevokeInvite", mock.Anything, 1, 7).Return(services.ErrInviteNotFound)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/invites/7", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("inviteId", "7")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.RevokeInvite(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invite not found", response["message"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAcceptInviteHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockInviteService)
+	handler := handlers.NewInviteHandler(mockService)
+
+	expected := &models.ChatMember{ChatID: 1, UserID: "bob", Role: models.RoleMember}
+	mockService.On("AcceptInvite", mock.Anything, "secret-token").Return(expected, nil)
+
+	req := httptest.NewRequest("POST", "/invites/secret-token/accept", nil)
+	req.SetPathValue("token", "secret-token")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AcceptInvite(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.ChatMember
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.ChatID)
+	assert.Equal(t, models.RoleMember, response.Role)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAcceptInviteHandler_Expired(t *testing.T) {
+	// Arrange
+	mockService := new(MockInviteService)
+	handler := handlers.NewInviteHandler(mockService)
+
+	mockService.On("AcceptInvite", mock.Anything, "secret-token").Return(nil, services.ErrInviteInvalid)
+
+	req := httptest.NewRequest("POST", "/invites/secret-token/accept", nil)
+	req.SetPathValue("token", "secret-token")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AcceptInvite(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusGone, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "GONE", response["error"])
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+// maxUserIDLength matches the size of users.id.
+const maxUserIDLength = 255
+
+var memberRoles = []string{models.RoleOwner, models.RoleAdmin, models.RoleMember, models.RoleReadOnly}
+
+type MemberHandler struct {
+	memberService services.MemberService
+}
+
+func NewMemberHandler(memberService services.MemberService) *MemberHandler {
+	return &MemberHandler{memberService: memberService}
+}
+
+func (h *MemberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	members, err := h.memberService.ListMembers(r.Context(), chatID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to list members", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dto.MemberList{Members: members}); err != nil {
+		slog.Error("Failed to serialize members", "error", err, "chatID", chatID)
+	}
+}
+
+func (h *MemberHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	var request dto.AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid json")
+		return
+	}
+
+	request.UserID = strings.TrimSpace(request.UserID)
+
+	if len(request.UserID) < 1 || len(request.UserID) > maxUserIDLength {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id length must be between 1 and 255")
+		return
+	}
+
+	if request.Role != "" && (request.Role == models.RoleOwner || !slices.Contains(memberRoles, request.Role)) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Role must be one of admin, member, read_only")
+		return
+	}
+
+	member, err := h.memberService.AddMember(r.Context(), chatID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to add members with this role")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMemberAlreadyExists):
+			writeJSONError(w, http.StatusConflict, "CONFLICT", "User is already a member of this chat")
+		default:
+			slog.Error("Failed to add member", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		slog.Error("Failed to serialize member", "error", err, "member", member)
+	}
+}
+
+func (h *MemberHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	userID := r.PathValue("userId")
+
+	var request dto.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid json")
+		return
+	}
+
+	if !slices.Contains(memberRoles, request.Role) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Role must be one of owner, admin, member, read_only")
+		return
+	}
+
+	member, err := h.memberService.UpdateMember(r.Context(), chatID, userID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to change the role of this member")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMemberNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Member not found")
+		default:
+			slog.Error("Failed to update member", "error", err, "chatID", chatID, "userID", userID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		slog.Error("Failed to serialize member", "error", err, "member", member)
+	}
+}
+
+func (h *MemberHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	userID := r.PathValue("userId")
+
+	if err := h.memberService.RemoveMember(r.Context(), chatID, userID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to remove this member")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMemberNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Member not found")
+		default:
+			slog.Error("Failed to remove member", "error", err, "chatID", chatID, "userID", userID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
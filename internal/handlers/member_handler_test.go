@@ -0,0 +1,316 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMemberService struct {
+	mock.Mock
+}
+
+func (m *MockMemberService) ListMembers(ctx context.Context, chatID int) ([]models.ChatMember, error) {
+	args := m.Called(ctx, chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChatMember), args.Error(1)
+}
+
+func (m *MockMemberService) AddMember(ctx context.Context, chatID int, req *dto.AddMemberRequest) (*models.ChatMember, error) {
+	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatMember), args.Error(1)
+}
+
+func (m *MockMemberService) UpdateMember(ctx context.Context, chatID int, userID string, req *dto.UpdateMemberRequest) (*models.ChatMember, error) {
+	args := m.Called(ctx, chatID, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChatMember), args.Error(1)
+}
+
+func (m *MockMemberService) RemoveMember(ctx context.Context, chatID int, userID string) error {
+	args := m.Called(ctx, chatID, userID)
+	return args.Error(0)
+}
+
+func TestListMembersHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	members := []models.ChatMember{
+		{ChatID: 1, UserID: "alice", User: &models.User{ID: "alice", Name: "Alice"}},
+		{ChatID: 1, UserID: "bob", User: &models.User{ID: "bob"}},
+	}
+	mockService.On("ListMembers", mock.Anything, 1).Return(members, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/members", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListMembers(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.MemberList
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Members, 2)
+	assert.Equal(t, "alice", response.Members[0].UserID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAddMemberHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	expected := &models.ChatMember{ChatID: 1, UserID: "bob"}
+	mockService.On("AddMember", mock.Anything, 1, &dto.AddMemberRequest{UserID: "bob"}).
+		Return(expected, nil)
+
+	req := httptest.NewRequest("POST", "/chats/1/members", bytes.NewBufferString(`{"user_id": " bob "}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.ChatMember
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", response.UserID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAddMemberHandler_EmptyUserID(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/chats/1/members", bytes.NewBufferString(`{"user_id": ""}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "AddMember")
+}
+
+func TestAddMemberHandler_AlreadyMember(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	mockService.On("AddMember", mock.Anything, 1, mock.Anything).
+		Return(nil, services.ErrMemberAlreadyExists)
+
+	req := httptest.NewRequest("POST", "/chats/1/members", bytes.NewBufferString(`{"user_id": "bob"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "CONFLICT", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAddMemberHandler_NotAMember(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	mockService.On("AddMember", mock.Anything, 1, mock.Anything).
+		Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("POST", "/chats/1/members", bytes.NewBufferString(`{"user_id": "bob"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestRemoveMemberHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	mockService.On("RemoveMember", mock.Anything, 1, "bob").Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/members/bob", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.RemoveMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestRemoveMemberHandler_NotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	mockService.On("RemoveMember", mock.Anything, 1, "bob").Return(services.ErrMemberNotFound)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/members/bob", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.RemoveMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Member not found", response["message"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAddMemberHandler_OwnerRole(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/chats/1/members", bytes.NewBufferString(`{"user_id": "bob", "role": "owner"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "AddMember")
+}
+
+func TestUpdateMemberHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	expected := &models.ChatMember{ChatID: 1, UserID: "bob", Role: models.RoleAdmin}
+	mockService.On("UpdateMember", mock.Anything, 1, "bob", &dto.UpdateMemberRequest{Role: models.RoleAdmin}).
+		Return(expected, nil)
+
+	req := httptest.NewRequest("PATCH", "/chats/1/members/bob", bytes.NewBufferString(`{"role": "admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.ChatMember
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, response.Role)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateMemberHandler_InvalidRole(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	req := httptest.NewRequest("PATCH", "/chats/1/members/bob", bytes.NewBufferString(`{"role": "superuser"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "UpdateMember")
+}
+
+func TestUpdateMemberHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockMemberService)
+	handler := handlers.NewMemberHandler(mockService)
+
+	mockService.On("UpdateMember", mock.Anything, 1, "bob", &dto.UpdateMemberRequest{Role: models.RoleOwner}).
+		Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("PATCH", "/chats/1/members/bob", bytes.NewBufferString(`{"role": "owner"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("userId", "bob")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateMember(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "FORBIDDEN", response["error"])
+
+	mockService.AssertExpectations(t)
+}
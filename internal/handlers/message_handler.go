@@ -6,11 +6,26 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jonx8/chat-service/internal/dto"
 	"github.com/jonx8/chat-service/internal/services"
 )
 
+const (
+	minMessageLength = 1
+	maxMessageLength = 5000
+
+	// maxEmojiLength matches the size of message_reactions.emoji. It leaves
+	// room for emoji built from several code points.
+	maxEmojiLength = 64
+
+	maxMessageAttachments = 10
+)
+
 type MessageHandler struct {
 	messageService services.MessageService
 }
@@ -32,14 +47,63 @@ func (h *MessageHandler) CreateMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(request.Text) < 1 || len(request.Text) > 5000 {
+	if len(request.AttachmentIDs) > maxMessageAttachments {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "A message may have at most 10 attachments")
+		return
+	}
+
+	if !areValidAttachmentIDs(request.AttachmentIDs) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "attachment_ids must be distinct positive integers")
+		return
+	}
+
+	// Messages consisting of attachments only may have no text.
+	if !isValidMessageText(request.Text) && (request.Text != "" || len(request.AttachmentIDs) == 0) {
 		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Message length must be between 1 and 5000")
 		return
 	}
 
-	message, err := h.messageService.CreateMessage(r.Context(), chatID, &request)
+	if request.ParentID != nil && *request.ParentID < 1 {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "parent_id must be a positive integer")
+		return
+	}
+
+	if request.ClientID != nil {
+		if !isValidUUID(*request.ClientID) {
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "client_id must be a UUID")
+			return
+		}
+		clientID := strings.ToLower(*request.ClientID)
+		request.ClientID = &clientID
+	}
+
+	if request.ExpiresAt != nil {
+		postedAt := time.Now()
+		if request.SendAt != nil {
+			postedAt = *request.SendAt
+		}
+		if !request.ExpiresAt.After(postedAt) {
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "expires_at must be later than the time the message is posted")
+			return
+		}
+	}
+
+	if request.SendAt != nil {
+		h.scheduleMessage(w, r, chatID, &request)
+		return
+	}
+
+	message, created, err := h.messageService.CreateMessage(r.Context(), chatID, &request)
 	if err != nil {
 		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to post in this chat")
+		case errors.Is(err, services.ErrInvalidParent):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "parent_id must reference a root message of this chat")
+		case errors.Is(err, services.ErrInvalidAttachment):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "attachment_ids must reference your unattached uploads to this chat")
 		case errors.Is(err, services.ErrChatNotFound):
 			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
 		default:
@@ -49,10 +113,357 @@ func (h *MessageHandler) CreateMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A retry deduplicated by client_id returns the message sent before.
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(message); err != nil {
+		slog.Error("Failed to serialize message", "error", err, "message", message)
+	}
+
+}
+
+// scheduleMessage handles a CreateMessage request with send_at. The message is
+// posted later, so the response only acknowledges the scheduled message.
+func (h *MessageHandler) scheduleMessage(w http.ResponseWriter, r *http.Request, chatID int, request *dto.CreateMessageRequest) {
+	if len(request.AttachmentIDs) > 0 {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Scheduled messages cannot have attachments")
+		return
+	}
+
+	if request.ClientID != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Scheduled messages cannot have a client_id")
+		return
+	}
+
+	if !request.SendAt.After(time.Now()) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "send_at must be in the future")
+		return
+	}
+
+	scheduled, err := h.messageService.ScheduleMessage(r.Context(), chatID, request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to post in this chat")
+		case errors.Is(err, services.ErrInvalidParent):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "parent_id must reference a root message of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to schedule message", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(scheduled); err != nil {
+		slog.Error("Failed to serialize scheduled message", "error", err, "scheduled", scheduled)
+	}
+}
+
+func (h *MessageHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	page, err := h.messageService.ListMessages(r.Context(), chatID, parsePageRequest(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCursor):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid pagination cursor")
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to list messages", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Error("Failed to serialize messages", "error", err, "chatID", chatID)
+	}
+}
+
+func (h *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	var request dto.UpdateMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid json")
+		return
+	}
+
+	if !isValidMessageText(request.Text) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Message length must be between 1 and 5000")
+		return
+	}
+
+	message, err := h.messageService.UpdateMessage(r.Context(), chatID, messageID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to edit this message")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		default:
+			slog.Error("Failed to update message", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(message); err != nil {
 		slog.Error("Failed to serialize message", "error", err, "message", message)
 	}
+}
+
+func (h *MessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.messageService.DeleteMessage(r.Context(), chatID, messageID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to delete this message")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		default:
+			slog.Error("Failed to delete message", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MessageHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.messageService.ListRevisions(r.Context(), chatID, messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		default:
+			slog.Error("Failed to list revisions", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(dto.MessageRevisions{Revisions: revisions}); err != nil {
+		slog.Error("Failed to serialize revisions", "error", err, "messageID", messageID)
+	}
+}
+
+func (h *MessageHandler) ListThread(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	thread, err := h.messageService.ListThread(r.Context(), chatID, messageID, parsePageRequest(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCursor):
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid pagination cursor")
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		default:
+			slog.Error("Failed to list thread", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(thread); err != nil {
+		slog.Error("Failed to serialize thread", "error", err, "messageID", messageID)
+	}
+}
+
+func (h *MessageHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	emoji := r.PathValue("emoji")
+	if !isValidEmoji(emoji) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid emoji")
+		return
+	}
+
+	if err := h.messageService.AddReaction(r.Context(), chatID, messageID, emoji); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to react in this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		default:
+			slog.Error("Failed to add reaction", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MessageHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	emoji := r.PathValue("emoji")
+	if !isValidEmoji(emoji) {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid emoji")
+		return
+	}
+
+	if err := h.messageService.RemoveReaction(r.Context(), chatID, messageID, emoji); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrReactionNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Reaction not found")
+		default:
+			slog.Error("Failed to remove reaction", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseMessagePath reads the chat and message ids of a message route. On
+// failure the error response is already written.
+func parseMessagePath(w http.ResponseWriter, r *http.Request) (chatID int, messageID int, ok bool) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return 0, 0, false
+	}
+
+	messageID, err = strconv.Atoi(r.PathValue("messageId"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Message ID path param must be integer")
+		return 0, 0, false
+	}
+
+	return chatID, messageID, true
+}
+
+func isValidMessageText(text string) bool {
+	return len(text) >= minMessageLength && len(text) <= maxMessageLength
+}
+
+func areValidAttachmentIDs(ids []int) bool {
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id < 1 || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+// isValidUUID accepts UUIDs in the canonical 8-4-4-4-12 hex form.
+func isValidUUID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, r := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !unicode.Is(unicode.ASCII_Hex_Digit, r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isValidEmoji accepts short strings without spaces or control characters.
+// Emoji are not checked against the Unicode list so that clients may use
+// newer emoji or custom shortcodes.
+func isValidEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiLength || !utf8.ValidString(emoji) {
+		return false
+	}
+
+	for _, r := range emoji {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
 
+	return true
 }
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jonx8/chat-service/internal/dto"
 	"github.com/jonx8/chat-service/internal/handlers"
@@ -21,14 +22,77 @@ type MockMessageService struct {
 	mock.Mock
 }
 
-func (m *MockMessageService) CreateMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.Message, error) {
+func (m *MockMessageService) CreateMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.Message, bool, error) {
 	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*models.Message), args.Bool(1), args.Error(2)
+}
+
+func (m *MockMessageService) ScheduleMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockMessageService) ListMessages(ctx context.Context, chatID int, req *dto.PageRequest) (*dto.MessagePage, error) {
+	args := m.Called(ctx, chatID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.MessagePage), args.Error(1)
+}
+
+func (m *MockMessageService) UpdateMessage(ctx context.Context, chatID int, messageID int, req *dto.UpdateMessageRequest) (*models.Message, error) {
+	args := m.Called(ctx, chatID, messageID, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Message), args.Error(1)
 }
 
+func (m *MockMessageService) DeleteMessage(ctx context.Context, chatID int, messageID int) error {
+	args := m.Called(ctx, chatID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) ListMessagesSince(ctx context.Context, chatID int, afterID int, limit int) ([]models.Message, error) {
+	args := m.Called(ctx, chatID, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockMessageService) ListThread(ctx context.Context, chatID int, messageID int, req *dto.PageRequest) (*dto.ThreadPage, error) {
+	args := m.Called(ctx, chatID, messageID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ThreadPage), args.Error(1)
+}
+
+func (m *MockMessageService) AddReaction(ctx context.Context, chatID int, messageID int, emoji string) error {
+	args := m.Called(ctx, chatID, messageID, emoji)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) RemoveReaction(ctx context.Context, chatID int, messageID int, emoji string) error {
+	args := m.Called(ctx, chatID, messageID, emoji)
+	return args.Error(0)
+}
+
+func (m *MockMessageService) ListRevisions(ctx context.Context, chatID int, messageID int) ([]models.MessageRevision, error) {
+	args := m.Called(ctx, chatID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MessageRevision), args.Error(1)
+}
+
 func TestCreateMessageHandler_Success(t *testing.T) {
 	// Arrange
 	mockService := new(MockMessageService)
@@ -42,7 +106,7 @@ func TestCreateMessageHandler_Success(t *testing.T) {
 
 	mockService.On("CreateMessage", mock.Anything, 123, mock.MatchedBy(func(req *dto.CreateMessageRequest) bool {
 		return req.Text == "Hello, world!"
-	})).Return(expectedMessage, nil)
+	})).Return(expectedMessage, true, nil)
 
 	reqBody := `{"text": "Hello, world!"}`
 	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(reqBody))
@@ -171,7 +235,7 @@ func TestCreateMessageHandler_ChatNotFound(t *testing.T) {
 	handler := handlers.NewMessageHandler(mockService)
 
 	mockService.On("CreateMessage", mock.Anything, 999, mock.Anything).
-		Return(nil, services.ErrChatNotFound)
+		Return(nil, false, services.ErrChatNotFound)
 
 	reqBody := `{"text": "Hello"}`
 	req := httptest.NewRequest("POST", "/chats/999/messages", bytes.NewBufferString(reqBody))
@@ -194,3 +258,802 @@ func TestCreateMessageHandler_ChatNotFound(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestListMessagesHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	expectedPage := &dto.MessagePage{
+		Messages: []models.Message{
+			{ID: 2, ChatID: 123, Text: "second"},
+			{ID: 1, ChatID: 123, Text: "first"},
+		},
+		Cursors: dto.PageCursors{Before: "next"},
+	}
+
+	mockService.On("ListMessages", mock.Anything, 123, &dto.PageRequest{Limit: 2}).
+		Return(expectedPage, nil)
+
+	req := httptest.NewRequest("GET", "/chats/123/messages?limit=2", nil)
+	req.SetPathValue("id", "123")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListMessages(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.MessagePage
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Messages, 2)
+	assert.Equal(t, "next", response.Cursors.Before)
+	assert.Empty(t, response.Cursors.After)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListMessagesHandler_ChatNotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("ListMessages", mock.Anything, 999, mock.Anything).
+		Return(nil, services.ErrChatNotFound)
+
+	req := httptest.NewRequest("GET", "/chats/999/messages", nil)
+	req.SetPathValue("id", "999")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListMessages(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "NOT_FOUND", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateMessageHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	editedAt := time.Now()
+	expectedMessage := &models.Message{
+		ID:       5,
+		ChatID:   123,
+		Text:     "Corrected",
+		EditedAt: &editedAt,
+	}
+
+	mockService.On("UpdateMessage", mock.Anything, 123, 5, &dto.UpdateMessageRequest{Text: "Corrected"}).
+		Return(expectedMessage, nil)
+
+	reqBody := `{"text": "Corrected"}`
+	req := httptest.NewRequest("PATCH", "/chats/123/messages/5", bytes.NewBufferString(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "5")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.Message
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Corrected", response.Text)
+	assert.NotNil(t, response.EditedAt)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateMessageHandler_TextTooLong(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	reqBody := `{"text": "` + strings.Repeat("a", 5001) + `"}`
+	req := httptest.NewRequest("PATCH", "/chats/123/messages/5", bytes.NewBufferString(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "5")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "BAD_REQUEST", response["error"])
+	assert.Contains(t, response["message"], "Message length must be between")
+}
+
+func TestUpdateMessageHandler_InvalidMessageID(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	req := httptest.NewRequest("PATCH", "/chats/123/messages/abc", bytes.NewBufferString(`{"text": "x"}`))
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "abc")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UpdateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteMessageHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("DeleteMessage", mock.Anything, 123, 5).Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/chats/123/messages/5", nil)
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "5")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DeleteMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteMessageHandler_NotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("DeleteMessage", mock.Anything, 123, 5).Return(services.ErrMessageNotFound)
+
+	req := httptest.NewRequest("DELETE", "/chats/123/messages/5", nil)
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "5")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DeleteMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "NOT_FOUND", response["error"])
+	assert.Equal(t, "Message not found", response["message"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListRevisionsHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	revisions := []models.MessageRevision{
+		{ID: 1, MessageID: 5, Text: "Original"},
+		{ID: 2, MessageID: 5, Text: "First edit"},
+	}
+
+	mockService.On("ListRevisions", mock.Anything, 123, 5).Return(revisions, nil)
+
+	req := httptest.NewRequest("GET", "/chats/123/messages/5/revisions", nil)
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "5")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListRevisions(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.MessageRevisions
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Revisions, 2)
+	assert.Equal(t, "Original", response.Revisions[0].Text)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListRevisionsHandler_MessageNotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("ListRevisions", mock.Anything, 123, 5).Return(nil, services.ErrMessageNotFound)
+
+	req := httptest.NewRequest("GET", "/chats/123/messages/5/revisions", nil)
+	req.SetPathValue("id", "123")
+	req.SetPathValue("messageId", "5")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListRevisions(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_NotAMember(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("CreateMessage", mock.Anything, 1, mock.Anything).
+		Return(nil, false, services.ErrForbidden)
+
+	reqBody := `{"text": "Hello"}`
+	req := httptest.NewRequest("POST", "/chats/1/messages", bytes.NewBufferString(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "FORBIDDEN", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListMessagesHandler_NotAMember(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("ListMessages", mock.Anything, 1, mock.Anything).
+		Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("GET", "/chats/1/messages", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListMessages(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "FORBIDDEN", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteMessageHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("DeleteMessage", mock.Anything, 1, 10).Return(services.ErrForbidden)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/messages/10", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "10")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.DeleteMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Not allowed to delete this message", response["message"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_Reply(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	parentID := 10
+	expectedMessage := &models.Message{ID: 11, ChatID: 1, ParentID: &parentID, Text: "Agreed"}
+
+	mockService.On("CreateMessage", mock.Anything, 1, &dto.CreateMessageRequest{Text: "Agreed", ParentID: &parentID}).
+		Return(expectedMessage, true, nil)
+
+	reqBody := `{"text": "Agreed", "parent_id": 10}`
+	req := httptest.NewRequest("POST", "/chats/1/messages", bytes.NewBufferString(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.Message
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, &parentID, response.ParentID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_InvalidParent(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("CreateMessage", mock.Anything, 1, mock.Anything).Return(nil, false, services.ErrInvalidParent)
+
+	reqBody := `{"text": "Agreed", "parent_id": 99}`
+	req := httptest.NewRequest("POST", "/chats/1/messages", bytes.NewBufferString(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "BAD_REQUEST", response["error"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListThreadHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	parentID := 10
+	expected := &dto.ThreadPage{
+		Root: &models.Message{ID: 10, ChatID: 1, Text: "Question", ReplyCount: 1},
+		Messages: []models.Message{
+			{ID: 11, ChatID: 1, ParentID: &parentID, Text: "Answer"},
+		},
+	}
+
+	mockService.On("ListThread", mock.Anything, 1, 10, &dto.PageRequest{Limit: 20}).Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/messages/10/thread", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "10")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListThread(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.ThreadPage
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.Root.ReplyCount)
+	assert.Len(t, response.Messages, 1)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListThreadHandler_NotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("ListThread", mock.Anything, 1, 99, mock.Anything).Return(nil, services.ErrMessageNotFound)
+
+	req := httptest.NewRequest("GET", "/chats/1/messages/99/thread", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "99")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListThread(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAddReactionHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("AddReaction", mock.Anything, 1, 10, "👍").Return(nil)
+
+	req := httptest.NewRequest("PUT", "/chats/1/messages/10/reactions/%F0%9F%91%8D", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "10")
+	req.SetPathValue("emoji", "👍")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddReaction(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestAddReactionHandler_InvalidEmoji(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	req := httptest.NewRequest("PUT", "/chats/1/messages/10/reactions/a%20b", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "10")
+	req.SetPathValue("emoji", "a b")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.AddReaction(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "AddReaction")
+}
+
+func TestRemoveReactionHandler_NotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("RemoveReaction", mock.Anything, 1, 10, "🎉").Return(services.ErrReactionNotFound)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/messages/10/reactions/%F0%9F%8E%89", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "10")
+	req.SetPathValue("emoji", "🎉")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.RemoveReaction(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Reaction not found", response["message"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListMessagesHandler_IncludesReactions(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	expected := &dto.MessagePage{
+		Messages: []models.Message{
+			{
+				ID:     10,
+				ChatID: 1,
+				Text:   "Release is out",
+				Reactions: []models.ReactionSummary{
+					{Emoji: "🎉", Count: 3, ReactedByMe: true},
+				},
+			},
+		},
+	}
+	mockService.On("ListMessages", mock.Anything, 1, mock.Anything).Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/messages", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListMessages(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.MessagePage
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Messages[0].Reactions, response.Messages[0].Reactions)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_AttachmentsOnly(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	expectedMessage := &models.Message{
+		ID:          1,
+		ChatID:      123,
+		Attachments: []models.Attachment{{ID: 5, ChatID: 123, FileName: "logo.png"}},
+	}
+
+	mockService.On("CreateMessage", mock.Anything, 123, mock.MatchedBy(func(req *dto.CreateMessageRequest) bool {
+		return req.Text == "" && len(req.AttachmentIDs) == 1 && req.AttachmentIDs[0] == 5
+	})).Return(expectedMessage, true, nil)
+
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(`{"attachment_ids": [5]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.Message
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Attachments, 1)
+	assert.Equal(t, "logo.png", response.Attachments[0].FileName)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_DuplicateAttachments(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(`{"text": "hi", "attachment_ids": [5, 5]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "CreateMessage")
+}
+
+func TestCreateMessageHandler_InvalidAttachment(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	mockService.On("CreateMessage", mock.Anything, 123, mock.Anything).Return(nil, false, services.ErrInvalidAttachment)
+
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(`{"attachment_ids": [7]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_Scheduled(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	expected := &models.ScheduledMessage{ID: 3, ChatID: 123, AuthorID: "test-user", Text: "Announcement", SendAt: sendAt}
+
+	mockService.On("ScheduleMessage", mock.Anything, 123, mock.MatchedBy(func(req *dto.CreateMessageRequest) bool {
+		return req.Text == "Announcement" && req.SendAt != nil && req.SendAt.Equal(sendAt)
+	})).Return(expected, nil)
+
+	body := `{"text": "Announcement", "send_at": "` + sendAt.Format(time.RFC3339) + `"}`
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response models.ScheduledMessage
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, response.ID)
+	assert.True(t, sendAt.Equal(response.SendAt))
+
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "CreateMessage")
+}
+
+func TestCreateMessageHandler_ScheduledInPast(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	body := `{"text": "Announcement", "send_at": "2020-01-01T00:00:00Z"}`
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ScheduleMessage")
+	mockService.AssertNotCalled(t, "CreateMessage")
+}
+
+func TestCreateMessageHandler_ScheduledWithAttachments(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	sendAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := `{"text": "Announcement", "attachment_ids": [5], "send_at": "` + sendAt + `"}`
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ScheduleMessage")
+}
+
+func TestCreateMessageHandler_ExpiresBeforeSendAt(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	sendAt := time.Now().Add(2 * time.Hour).UTC()
+	expiresAt := sendAt.Add(-time.Hour)
+	body := `{"text": "Announcement", "send_at": "` + sendAt.Format(time.RFC3339) + `", "expires_at": "` + expiresAt.Format(time.RFC3339) + `"}`
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ScheduleMessage")
+}
+
+func TestCreateMessageHandler_ClientID(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	clientID := "0e0b7c4a-9d7e-4a57-a1f5-3b1f3b0c8e11"
+	expected := &models.Message{ID: 1, ChatID: 123, Text: "Hello", ClientID: &clientID}
+
+	mockService.On("CreateMessage", mock.Anything, 123, mock.MatchedBy(func(req *dto.CreateMessageRequest) bool {
+		return req.ClientID != nil && *req.ClientID == clientID
+	})).Return(expected, true, nil)
+
+	body := `{"text": "Hello", "client_id": "0E0B7C4A-9D7E-4A57-A1F5-3B1F3B0C8E11"}`
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.Message
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, &clientID, response.ClientID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_DeduplicatedRetry(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	clientID := "0e0b7c4a-9d7e-4a57-a1f5-3b1f3b0c8e11"
+	expected := &models.Message{ID: 1, ChatID: 123, Text: "Hello", ClientID: &clientID}
+	mockService.On("CreateMessage", mock.Anything, 123, mock.Anything).Return(expected, false, nil)
+
+	body := `{"text": "Hello", "client_id": "0e0b7c4a-9d7e-4a57-a1f5-3b1f3b0c8e11"}`
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.Message
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.ID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateMessageHandler_InvalidClientID(t *testing.T) {
+	// Arrange
+	mockService := new(MockMessageService)
+	handler := handlers.NewMessageHandler(mockService)
+
+	req := httptest.NewRequest("POST", "/chats/123/messages", bytes.NewBufferString(`{"text": "Hello", "client_id": "not-a-uuid"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "123")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.CreateMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "CreateMessage")
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/jonx8/chat-service/internal/dto"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func parsePageRequest(r *http.Request) *dto.PageRequest {
+	query := r.URL.Query()
+
+	return &dto.PageRequest{
+		Limit:  parseLimit(query),
+		Before: query.Get("before"),
+		After:  query.Get("after"),
+	}
+}
+
+// parseLimit returns the limit query param, falling back to the default when
+// it is missing or out of range.
+func parseLimit(query url.Values) int {
+	if limitParam := query.Get("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val >= 1 && val <= maxPageLimit {
+			return val
+		}
+	}
+	return defaultPageLimit
+}
+
+// parseOffset returns the offset query param. Unlike the limit, an invalid
+// offset is rejected rather than silently replaced.
+func parseOffset(query url.Values) (int, bool) {
+	offsetParam := query.Get("offset")
+	if offsetParam == "" {
+		return 0, true
+	}
+
+	val, err := strconv.Atoi(offsetParam)
+	if err != nil || val < 0 {
+		return 0, false
+	}
+	return val, true
+}
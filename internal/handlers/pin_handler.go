@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+type PinHandler struct {
+	pinService services.PinService
+}
+
+func NewPinHandler(pinService services.PinService) *PinHandler {
+	return &PinHandler{pinService: pinService}
+}
+
+func (h *PinHandler) PinMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	pin, err := h.pinService.PinMessage(r.Context(), chatID, messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to pin messages in this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrMessageNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Message not found")
+		case errors.Is(err, services.ErrPinLimitExceeded):
+			writeJSONError(w, http.StatusConflict, "CONFLICT", "Too many pinned messages in this chat")
+		default:
+			slog.Error("Failed to pin message", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(pin); err != nil {
+		slog.Error("Failed to serialize pin", "error", err, "pin", pin)
+	}
+}
+
+func (h *PinHandler) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, messageID, ok := parseMessagePath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.pinService.UnpinMessage(r.Context(), chatID, messageID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "Not allowed to unpin messages in this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		case errors.Is(err, services.ErrPinNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Pinned message not found")
+		default:
+			slog.Error("Failed to unpin message", "error", err, "chatID", chatID, "messageID", messageID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
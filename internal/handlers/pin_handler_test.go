@@ -0,0 +1,165 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPinService struct {
+	mock.Mock
+}
+
+func (m *MockPinService) PinMessage(ctx context.Context, chatID int, messageID int) (*models.PinnedMessage, error) {
+	args := m.Called(ctx, chatID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PinnedMessage), args.Error(1)
+}
+
+func (m *MockPinService) UnpinMessage(ctx context.Context, chatID int, messageID int) error {
+	args := m.Called(ctx, chatID, messageID)
+	return args.Error(0)
+}
+
+func TestPinMessageHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockPinService)
+	handler := handlers.NewPinHandler(mockService)
+
+	pinnedBy := "alice"
+	expected := &models.PinnedMessage{
+		ChatID:    1,
+		MessageID: 2,
+		PinnedBy:  &pinnedBy,
+		Message:   &models.Message{ID: 2, ChatID: 1, Text: "Rules"},
+	}
+	mockService.On("PinMessage", mock.Anything, 1, 2).Return(expected, nil)
+
+	req := httptest.NewRequest("PUT", "/chats/1/pins/2", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "2")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.PinMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PinnedMessage
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, response.MessageID)
+	assert.Equal(t, "alice", *response.PinnedBy)
+	assert.Equal(t, "Rules", response.Message.Text)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestPinMessageHandler_LimitExceeded(t *testing.T) {
+	// Arrange
+	mockService := new(MockPinService)
+	handler := handlers.NewPinHandler(mockService)
+
+	mockService.On("PinMessage", mock.Anything, 1, 2).Return(nil, services.ErrPinLimitExceeded)
+
+	req := httptest.NewRequest("PUT", "/chats/1/pins/2", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "2")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.PinMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestPinMessageHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockPinService)
+	handler := handlers.NewPinHandler(mockService)
+
+	mockService.On("PinMessage", mock.Anything, 1, 2).Return(nil, services.ErrForbidden)
+
+	req := httptest.NewRequest("PUT", "/chats/1/pins/2", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "2")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.PinMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestPinMessageHandler_InvalidMessageID(t *testing.T) {
+	// Arrange
+	mockService := new(MockPinService)
+	handler := handlers.NewPinHandler(mockService)
+
+	req := httptest.NewRequest("PUT", "/chats/1/pins/abc", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "abc")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.PinMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "PinMessage")
+}
+
+func TestUnpinMessageHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockPinService)
+	handler := handlers.NewPinHandler(mockService)
+
+	mockService.On("UnpinMessage", mock.Anything, 1, 2).Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/pins/2", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "2")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UnpinMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUnpinMessageHandler_NotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockPinService)
+	handler := handlers.NewPinHandler(mockService)
+
+	mockService.On("UnpinMessage", mock.Anything, 1, 2).Return(services.ErrPinNotFound)
+
+	req := httptest.NewRequest("DELETE", "/chats/1/pins/2", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("messageId", "2")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.UnpinMessage(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
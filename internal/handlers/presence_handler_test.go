@@ -0,0 +1,135 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPresenceService struct {
+	mock.Mock
+}
+
+func (m *MockPresenceService) Connect(ctx context.Context, chatID int) (func(), error) {
+	args := m.Called(ctx, chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(func()), args.Error(1)
+}
+
+func (m *MockPresenceService) Typing(ctx context.Context, chatID int) error {
+	args := m.Called(ctx, chatID)
+	return args.Error(0)
+}
+
+func (m *MockPresenceService) ListPresence(ctx context.Context, chatID int) ([]models.Presence, error) {
+	args := m.Called(ctx, chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Presence), args.Error(1)
+}
+
+// newConnectingPresenceService returns a presence service accepting any
+// number of connections, for handlers that only track presence on the side.
+func newConnectingPresenceService() *MockPresenceService {
+	presenceService := new(MockPresenceService)
+	presenceService.On("Connect", mock.Anything, mock.Anything).Return(func() {}, nil).Maybe()
+	return presenceService
+}
+
+func TestTypingHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockPresenceService)
+	handler := handlers.NewPresenceHandler(mockService)
+
+	mockService.On("Typing", mock.Anything, 1).Return(nil)
+
+	req := httptest.NewRequest("POST", "/chats/1/typing", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Typing(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestTypingHandler_Forbidden(t *testing.T) {
+	// Arrange
+	mockService := new(MockPresenceService)
+	handler := handlers.NewPresenceHandler(mockService)
+
+	mockService.On("Typing", mock.Anything, 1).Return(services.ErrForbidden)
+
+	req := httptest.NewRequest("POST", "/chats/1/typing", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Typing(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListPresenceHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockPresenceService)
+	handler := handlers.NewPresenceHandler(mockService)
+
+	users := []models.Presence{
+		{UserID: "alice", Status: models.PresenceOnline, Typing: true},
+		{UserID: "bob", Status: models.PresenceAway},
+	}
+	mockService.On("ListPresence", mock.Anything, 1).Return(users, nil)
+
+	req := httptest.NewRequest("GET", "/chats/1/presence", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListPresence(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.PresenceList
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, users, response.Users)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListPresenceHandler_ChatNotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockPresenceService)
+	handler := handlers.NewPresenceHandler(mockService)
+
+	mockService.On("ListPresence", mock.Anything, 999).Return(nil, services.ErrChatNotFound)
+
+	req := httptest.NewRequest("GET", "/chats/999/presence", nil)
+	req.SetPathValue("id", "999")
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.ListPresence(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+const maxSearchQueryLength = 200
+
+type SearchHandler struct {
+	searchService services.SearchService
+}
+
+func NewSearchHandler(searchService services.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	request := dto.SearchRequest{
+		Query: strings.TrimSpace(query.Get("q")),
+		Limit: parseLimit(query),
+	}
+
+	if len(request.Query) < 1 || len(request.Query) > maxSearchQueryLength {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Query length must be between 1 and 200")
+		return
+	}
+
+	if chatParam := query.Get("chat_id"); chatParam != "" {
+		chatID, err := strconv.Atoi(chatParam)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "chat_id must be integer")
+			return
+		}
+		request.ChatID = chatID
+	}
+
+	offset, ok := parseOffset(query)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "Offset must be a non-negative integer")
+		return
+	}
+	request.Offset = offset
+
+	result, err := h.searchService.SearchMessages(r.Context(), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		default:
+			slog.Error("Failed to search messages", "error", err, "query", request.Query)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.Error("Failed to serialize search result", "error", err)
+	}
+}
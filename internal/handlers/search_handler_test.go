@@ -0,0 +1,132 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSearchService struct {
+	mock.Mock
+}
+
+func (m *MockSearchService) SearchMessages(ctx context.Context, req *dto.SearchRequest) (*dto.SearchResult, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.SearchResult), args.Error(1)
+}
+
+func TestSearchHandler_Success(t *testing.T) {
+	// Arrange
+	mockService := new(MockSearchService)
+	handler := handlers.NewSearchHandler(mockService)
+
+	expected := &dto.SearchResult{
+		Hits: []models.SearchHit{
+			{MessageID: 10, ChatID: 3, Rank: 0.6, Snippet: "say <mark>hello</mark>"},
+		},
+		Total: 1,
+	}
+
+	mockService.On("SearchMessages", mock.Anything, &dto.SearchRequest{Query: "hello", ChatID: 3, Limit: 5, Offset: 10}).
+		Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/search?q=hello&chat_id=3&limit=5&offset=10", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Search(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.SearchResult
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.Total)
+	assert.Len(t, response.Hits, 1)
+	assert.Equal(t, "say <mark>hello</mark>", response.Hits[0].Snippet)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchHandler_EmptyQuery(t *testing.T) {
+	// Arrange
+	mockService := new(MockSearchService)
+	handler := handlers.NewSearchHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/search?q=+", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Search(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "BAD_REQUEST", response["error"])
+}
+
+func TestSearchHandler_QueryTooLong(t *testing.T) {
+	// Arrange
+	mockService := new(MockSearchService)
+	handler := handlers.NewSearchHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/search?q="+strings.Repeat("a", 201), nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Search(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSearchHandler_InvalidChatID(t *testing.T) {
+	// Arrange
+	mockService := new(MockSearchService)
+	handler := handlers.NewSearchHandler(mockService)
+
+	req := httptest.NewRequest("GET", "/search?q=hello&chat_id=abc", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Search(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSearchHandler_ServiceError(t *testing.T) {
+	// Arrange
+	mockService := new(MockSearchService)
+	handler := handlers.NewSearchHandler(mockService)
+
+	mockService.On("SearchMessages", mock.Anything, mock.Anything).
+		Return(nil, errors.New("boom"))
+
+	req := httptest.NewRequest("GET", "/search?q=hello", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.Search(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockService.AssertExpectations(t)
+}
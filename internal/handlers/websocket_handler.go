@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jonx8/chat-service/internal/auth"
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/services"
+)
+
+const (
+	// sendBufferSize is the number of events queued per connection before the
+	// connection is considered too slow and dropped.
+	sendBufferSize = 64
+
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxInboundSize = 512
+)
+
+type WebSocketHandler struct {
+	chatService     services.ChatService
+	presenceService services.PresenceService
+	broker          *events.Broker
+	upgrader        websocket.Upgrader
+}
+
+func NewWebSocketHandler(chatService services.ChatService, presenceService services.PresenceService, broker *events.Broker) *WebSocketHandler {
+	return &WebSocketHandler{
+		chatService:     chatService,
+		presenceService: presenceService,
+		broker:          broker,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+}
+
+func (h *WebSocketHandler) ServeChat(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "BAD_REQUEST", "ID path param must be integer")
+		return
+	}
+
+	if err := h.chatService.CheckAccess(r.Context(), chatID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+		case errors.Is(err, services.ErrForbidden):
+			writeJSONError(w, http.StatusForbidden, "FORBIDDEN", "You are not a member of this chat")
+		case errors.Is(err, services.ErrChatNotFound):
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "Chat not found")
+		default:
+			slog.Error("Failed to check chat", "error", err, "chatID", chatID)
+			writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		}
+		return
+	}
+
+	leave, err := h.presenceService.Connect(r.Context(), chatID)
+	if err != nil {
+		slog.Error("Failed to track presence", "error", err, "chatID", chatID)
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "Internal Server Error")
+		return
+	}
+	defer leave()
+
+	// Subscribe before the handshake completes so that no event published
+	// after the client sees the upgrade response is missed.
+	identity, _ := auth.FromContext(r.Context())
+	sub := h.broker.Subscribe(chatID, identity.Subject, sendBufferSize)
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error.
+		slog.Warn("Failed to upgrade connection", "error", err, "chatID", chatID)
+		sub.Close()
+		return
+	}
+
+	go readPump(conn, sub)
+	writePump(conn, sub)
+}
+
+// readPump discards client frames and keeps the read deadline fresh on pong.
+// It cancels the subscription once the client goes away.
+func readPump(conn *websocket.Conn, sub *events.Subscription) {
+	defer sub.Close()
+
+	conn.SetReadLimit(maxInboundSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump delivers subscription events and pings until the subscription is
+// closed, either by readPump or by the broker during shutdown.
+func writePump(conn *websocket.Conn, sub *events.Subscription) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		sub.Close()
+		_ = conn.Close()
+	}()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+				_ = conn.WriteMessage(websocket.CloseMessage, message)
+				return
+			}
+
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+
+			if event.Type == events.TypeChatDeleted {
+				message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "chat deleted")
+				_ = conn.WriteMessage(websocket.CloseMessage, message)
+				return
+			}
+
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
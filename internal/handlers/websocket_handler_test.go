@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/handlers"
+	"github.com/jonx8/chat-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebSocketServer(t *testing.T, mockService *MockChatService, broker *events.Broker) *httptest.Server {
+	t.Helper()
+
+	handler := handlers.NewWebSocketHandler(mockService, newConnectingPresenceService(), broker)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /chats/{id}/ws", handler.ServeChat)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWebSocketHandler_DeliversEvents(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	broker := events.NewBroker()
+	server := newWebSocketServer(t, mockService, broker)
+
+	mockService.On("CheckAccess", mock.Anything, 1).Return(nil)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/chats/1/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Act
+	broker.Publish(events.Event{Type: events.TypeMessageCreated, ChatID: 1, Data: "hello"})
+
+	// Assert
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	var event events.Event
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, events.TypeMessageCreated, event.Type)
+	assert.Equal(t, 1, event.ChatID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestWebSocketHandler_ClosesOnShutdown(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	broker := events.NewBroker()
+	server := newWebSocketServer(t, mockService, broker)
+
+	mockService.On("CheckAccess", mock.Anything, 1).Return(nil)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/chats/1/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Act
+	broker.Close()
+
+	// Assert
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "unexpected error: %v", err)
+}
+
+func TestWebSocketHandler_ChatNotFound(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	broker := events.NewBroker()
+	server := newWebSocketServer(t, mockService, broker)
+
+	mockService.On("CheckAccess", mock.Anything, 999).Return(services.ErrChatNotFound)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/chats/999/ws"
+
+	// Act
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	// Assert
+	assert.Error(t, err)
+	require.NotNil(t, resp)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestWebSocketHandler_NotAMember(t *testing.T) {
+	// Arrange
+	mockService := new(MockChatService)
+	broker := events.NewBroker()
+	server := newWebSocketServer(t, mockService, broker)
+
+	mockService.On("CheckAccess", mock.Anything, 1).Return(services.ErrForbidden)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/chats/1/ws"
+
+	// Act
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	// Assert
+	assert.Error(t, err)
+	require.NotNil(t, resp)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.False(t, broker.HasSubscribers(1))
+
+	mockService.AssertExpectations(t)
+}
@@ -1,19 +1,278 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// User is a caller known by the subject of their access token.
+type User struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Kinds of chats. Direct chats are private conversations between two users;
+// they have no title and no owner.
+const (
+	ChatKindGroup  = "group"
+	ChatKindDirect = "direct"
+)
 
 type Chat struct {
 	ID        int       `gorm:"primaryKey" json:"id"`
+	Kind      string    `json:"kind"`
 	Title     string    `json:"title"`
+	OwnerID   *string   `json:"owner_id"`
 	CreatedAt time.Time `json:"created_at"`
 	Messages  []Message `json:"messages"`
+
+	Pinned []PinnedMessage `json:"pinned"`
+
+	Retention Retention `gorm:"embedded;embeddedPrefix:retention_" json:"retention"`
+
+	DirectLowUserID  *string `json:"-"`
+	DirectHighUserID *string `json:"-"`
+
+	Owner *User `json:"owner,omitempty"`
 }
 
+// Message is either a root message of a chat or, when ParentID is set, a reply
+// in the thread of a root message. ReplyCount and LastReplyAt summarize the
+// thread of root messages and, like Reactions, are not stored.
 type Message struct {
-	ID        int       `gorm:"primaryKey" json:"id"`
-	ChatID    int       `json:"chat_id"`
+	ID        int        `gorm:"primaryKey" json:"id"`
+	ChatID    int        `json:"chat_id"`
+	ParentID  *int       `json:"parent_id"`
+	AuthorID  *string    `json:"author_id"`
+	ClientID  *string    `json:"client_id"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	ReplyCount  int64             `gorm:"-" json:"reply_count"`
+	LastReplyAt *time.Time        `gorm:"-" json:"last_reply_at"`
+	Reactions   []ReactionSummary `gorm:"-" json:"reactions,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	Chat   *Chat `json:"-"`
+	Author *User `json:"author,omitempty"`
+}
+
+// Roles of chat members, from the most to the least privileged.
+const (
+	RoleOwner    = "owner"
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read_only"
+)
+
+type ChatMember struct {
+	ChatID   int       `gorm:"primaryKey" json:"chat_id"`
+	UserID   string    `gorm:"primaryKey" json:"user_id"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `gorm:"autoCreateTime" json:"joined_at"`
+
+	// The read position is the last read message together with its creation
+	// time, which stays valid after the message is deleted.
+	LastReadMessageID *int       `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"-"`
+
+	User *User `json:"user,omitempty"`
+	Chat *Chat `json:"-"`
+}
+
+// ChatInvite lets users join a chat on their own. Only a hash of the token is
+// stored; nil ExpiresAt and MaxUses mean the invite never expires and may be
+// used any number of times.
+type ChatInvite struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	ChatID    int        `json:"chat_id"`
+	TokenHash string     `json:"-"`
+	CreatedBy *string    `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// States of thumbnail generation. Attachments that are not images have no
+// thumbnail status.
+const (
+	ThumbnailPending = "pending"
+	ThumbnailReady   = "ready"
+	ThumbnailFailed  = "failed"
+)
+
+// Attachment is a file uploaded to a chat. It is linked to a message when the
+// uploader sends one referencing it; until then MessageID is nil. The content
+// itself is kept in a blob store under StorageKey. Width and Height are known
+// for images once their thumbnail has been generated.
+// ThumbnailClaimedUntil is set while a worker generates the thumbnail.
+type Attachment struct {
+	ID          int       `gorm:"primaryKey" json:"id"`
+	ChatID      int       `json:"chat_id"`
+	MessageID   *int      `json:"message_id"`
+	UploaderID  *string   `json:"uploader_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       *int      `json:"width"`
+	Height      *int      `json:"height"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	ThumbnailKey          *string    `json:"-"`
+	ThumbnailStatus       *string    `json:"-"`
+	ThumbnailClaimedUntil *time.Time `json:"-"`
+}
+
+// OrphanedBlob is a blob of a deleted attachment waiting to be removed from
+// the blob store.
+type OrphanedBlob struct {
+	StorageKey string `gorm:"primaryKey"`
+	CreatedAt  time.Time
+}
+
+// URL is the path the attachment is downloaded from.
+func (a Attachment) URL() string {
+	return fmt.Sprintf("/chats/%d/attachments/%d", a.ChatID, a.ID)
+}
+
+// ThumbnailURL is the path of the thumbnail, or nil while there is none.
+func (a Attachment) ThumbnailURL() *string {
+	if a.ThumbnailKey == nil {
+		return nil
+	}
+
+	url := a.URL() + "/thumbnail"
+	return &url
+}
+
+func (a Attachment) MarshalJSON() ([]byte, error) {
+	type attachment Attachment
+	return json.Marshal(struct {
+		attachment
+		URL          string  `json:"url"`
+		ThumbnailURL *string `json:"thumbnail_url"`
+	}{attachment(a), a.URL(), a.ThumbnailURL()})
+}
+
+type MessageReaction struct {
+	MessageID int       `gorm:"primaryKey" json:"message_id"`
+	UserID    string    `gorm:"primaryKey" json:"user_id"`
+	Emoji     string    `gorm:"primaryKey" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Retention limits the history a chat keeps: messages older than MaxAge
+// seconds and messages beyond the MaxMessages newest ones expire. Nil fields
+// do not limit the history.
+type Retention struct {
+	MaxAge      *int `json:"max_age"`
+	MaxMessages *int `json:"max_messages"`
+}
+
+// ScheduledMessage is a message waiting to be posted at SendAt.
+type ScheduledMessage struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	ChatID    int        `json:"chat_id"`
+	ParentID  *int       `json:"parent_id"`
+	AuthorID  string     `json:"author_id"`
+	Text      string     `json:"text"`
+	SendAt    time.Time  `json:"send_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	Author *User `json:"author,omitempty"`
+}
+
+// IdempotencyKey records a request sent with an Idempotency-Key header. The
+// response is filled in once the request has completed, so that retries can
+// be answered with it.
+type IdempotencyKey struct {
+	UserID      string `gorm:"primaryKey"`
+	Key         string `gorm:"primaryKey"`
+	Fingerprint string
+	StatusCode  *int
+	ContentType *string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// PinnedMessage marks a message as pinned in its chat.
+type PinnedMessage struct {
+	ChatID    int       `gorm:"primaryKey" json:"chat_id"`
+	MessageID int       `gorm:"primaryKey" json:"message_id"`
+	PinnedBy  *string   `json:"pinned_by"`
+	PinnedAt  time.Time `gorm:"autoCreateTime" json:"pinned_at"`
+
+	Message *Message `json:"message,omitempty"`
+}
+
+// ReactionSummary aggregates the reactions to a message with one emoji as
+// seen by a particular user.
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int64  `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// Presence statuses. Users are online while they are active, away while they
+// are connected but idle, and offline otherwise.
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// Presence is the ephemeral status of a user in a chat. It is never stored.
+type Presence struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"`
+	Typing bool   `json:"typing"`
+}
+
+// ChatSummary is a read-only projection of a chat used by chat listings.
+type ChatSummary struct {
+	ID             int             `json:"id"`
+	Kind           string          `json:"kind"`
+	Title          string          `json:"title"`
+	CreatedAt      time.Time       `json:"created_at"`
+	LastActivityAt time.Time       `json:"last_activity_at"`
+	MessageCount   int64           `json:"message_count"`
+	UnreadCount    int64           `json:"unread_count"`
+	LastMessage    *MessagePreview `json:"last_message"`
+}
+
+type MessagePreview struct {
+	ID        int       `json:"id"`
 	Text      string    `json:"text"`
 	CreatedAt time.Time `json:"created_at"`
+}
 
-	Chat *Chat `json:"-"`
+// MessageRevision is a superseded version of a message. CreatedAt is the time
+// the version was written and ReplacedAt the time it was edited or deleted.
+type MessageRevision struct {
+	ID         int       `gorm:"primaryKey" json:"id"`
+	MessageID  int       `json:"message_id"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// SearchHit is a message matching a full-text query. Snippet contains the
+// matching fragments with the matched words wrapped in <mark> tags. The rest
+// of the snippet is HTML-escaped.
+type SearchHit struct {
+	MessageID int       `json:"message_id"`
+	ChatID    int       `json:"chat_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Rank      float64   `json:"rank"`
+	Snippet   string    `json:"snippet"`
 }
@@ -0,0 +1,305 @@
+package presence
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/models"
+)
+
+const (
+	// typingTTL is how long a typing heartbeat keeps a user typing. Clients
+	// are expected to repeat the heartbeat every few seconds while typing.
+	typingTTL = 5 * time.Second
+
+	// sweepInterval is how often expired states are detected.
+	sweepInterval = time.Second
+
+	// outboxSize is the number of state changes that may wait to be sent to
+	// the other instances.
+	outboxSize = 256
+)
+
+// Tracker keeps the presence of users per chat in memory. A user is present
+// while they hold a live connection to the chat or their last heartbeat is
+// younger than the TTL; present users are online unless they have been idle
+// for awayAfter.
+//
+// The state tracked by this instance is sent to the other instances through
+// the cluster publisher, and repeated while the user is present, so that the
+// other instances can forget it once it is no longer repeated. The states
+// received from them with ApplyRemote are merged with the local one, and every
+// change of the merged state is published to the local subscribers of the chat.
+type Tracker struct {
+	local     events.Publisher
+	cluster   events.Publisher
+	ttl       time.Duration
+	awayAfter time.Duration
+	now       func() time.Time
+
+	// outbox decouples sending to the cluster, which may block on the
+	// network, from the callers holding mu.
+	outbox chan events.Event
+
+	mu    sync.Mutex
+	chats map[int]map[string]*entry
+}
+
+type entry struct {
+	connections   int
+	lastHeartbeat time.Time
+	lastActive    time.Time
+	typingUntil   time.Time
+
+	// remote holds the states reported by other instances by their origin.
+	remote map[string]remoteState
+
+	// broadcast is the local state last sent to the cluster, at broadcastAt.
+	broadcast   models.Presence
+	broadcastAt time.Time
+
+	// reported is the merged state last published to local subscribers.
+	reported models.Presence
+}
+
+// remoteState is the state of a user on another instance. It is forgotten at
+// expiresAt unless the instance repeats it.
+type remoteState struct {
+	state     models.Presence
+	expiresAt time.Time
+}
+
+func NewTracker(local events.Publisher, cluster events.Publisher, ttl time.Duration, awayAfter time.Duration) *Tracker {
+	return &Tracker{
+		local:     local,
+		cluster:   cluster,
+		ttl:       ttl,
+		awayAfter: awayAfter,
+		now:       time.Now,
+		outbox:    make(chan events.Event, outboxSize),
+		chats:     make(map[int]map[string]*entry),
+	}
+}
+
+// Connect records a live connection of a user to a chat. The returned
+// function ends it and may be called more than once.
+func (t *Tracker) Connect(chatID int, userID string) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e := t.entry(chatID, userID)
+	e.connections++
+	e.lastActive = now
+	t.refresh(chatID, userID, e, now)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.disconnect(chatID, userID) })
+	}
+}
+
+func (t *Tracker) disconnect(chatID int, userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.chats[chatID][userID]
+	if !ok {
+		return
+	}
+
+	e.connections--
+	t.refresh(chatID, userID, e, t.now())
+}
+
+// Typing records a typing heartbeat, which also counts as activity.
+func (t *Tracker) Typing(chatID int, userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e := t.entry(chatID, userID)
+	e.lastHeartbeat = now
+	e.lastActive = now
+	e.typingUntil = now.Add(typingTTL)
+	t.refresh(chatID, userID, e, now)
+}
+
+// ApplyRemote records the state of a user reported by another instance.
+func (t *Tracker) ApplyRemote(origin string, chatID int, state models.Presence) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e := t.entry(chatID, state.UserID)
+	if state.Status == models.PresenceOffline {
+		delete(e.remote, origin)
+	} else {
+		if e.remote == nil {
+			e.remote = make(map[string]remoteState)
+		}
+		e.remote[origin] = remoteState{state: state, expiresAt: now.Add(t.ttl)}
+	}
+	t.refresh(chatID, state.UserID, e, now)
+}
+
+// List returns the users present in a chat on any instance ordered by id.
+func (t *Tracker) List(chatID int) []models.Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	users := []models.Presence{}
+	for userID, e := range t.chats[chatID] {
+		if state := t.merged(userID, e, now); state.Status != models.PresenceOffline {
+			users = append(users, state)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users
+}
+
+// Run sends local changes to the cluster and publishes the changes caused by
+// expiring heartbeats until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-t.outbox:
+			t.cluster.Publish(event)
+		case <-ticker.C:
+			t.sweep()
+		}
+	}
+}
+
+func (t *Tracker) sweep() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for chatID, users := range t.chats {
+		for userID, e := range users {
+			t.refresh(chatID, userID, e, now)
+		}
+	}
+}
+
+func (t *Tracker) entry(chatID int, userID string) *entry {
+	users, ok := t.chats[chatID]
+	if !ok {
+		users = make(map[string]*entry)
+		t.chats[chatID] = users
+	}
+
+	e, ok := users[userID]
+	if !ok {
+		offline := models.Presence{UserID: userID, Status: models.PresenceOffline}
+		e = &entry{broadcast: offline, reported: offline}
+		users[userID] = e
+	}
+
+	return e
+}
+
+func (t *Tracker) state(userID string, e *entry, now time.Time) models.Presence {
+	state := models.Presence{UserID: userID, Status: models.PresenceOffline}
+
+	if e.connections > 0 || now.Before(e.lastHeartbeat.Add(t.ttl)) {
+		state.Status = models.PresenceAway
+		if now.Before(e.lastActive.Add(t.awayAfter)) {
+			state.Status = models.PresenceOnline
+		}
+		state.Typing = now.Before(e.typingUntil)
+	}
+
+	return state
+}
+
+// merged combines the local state of a user with the unexpired states reported
+// by other instances: the user has the most present status and is typing if
+// they are typing anywhere.
+func (t *Tracker) merged(userID string, e *entry, now time.Time) models.Presence {
+	state := t.state(userID, e, now)
+	for _, remote := range e.remote {
+		if !now.Before(remote.expiresAt) {
+			continue
+		}
+		if statusRank(remote.state.Status) > statusRank(state.Status) {
+			state.Status = remote.state.Status
+		}
+		state.Typing = state.Typing || remote.state.Typing
+	}
+
+	return state
+}
+
+func statusRank(status string) int {
+	switch status {
+	case models.PresenceOnline:
+		return 2
+	case models.PresenceAway:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// refresh sends the local state of a user to the cluster when it changed or is
+// due to be repeated, publishes the merged state if it changed and forgets
+// users who went offline everywhere. It must be called with the lock held.
+func (t *Tracker) refresh(chatID int, userID string, e *entry, now time.Time) {
+	local := t.state(userID, e, now)
+	repeat := local.Status != models.PresenceOffline && !now.Before(e.broadcastAt.Add(t.ttl/3))
+	if local != e.broadcast || repeat {
+		e.broadcast = local
+		e.broadcastAt = now
+		t.send(events.Event{
+			Type:   events.TypePresenceChanged,
+			ChatID: chatID,
+			Data:   local,
+		})
+	}
+
+	for origin, remote := range e.remote {
+		if !now.Before(remote.expiresAt) {
+			delete(e.remote, origin)
+		}
+	}
+
+	state := t.merged(userID, e, now)
+	if state != e.reported {
+		e.reported = state
+		t.local.Publish(events.Event{
+			Type:   events.TypePresenceChanged,
+			ChatID: chatID,
+			Data:   state,
+		})
+	}
+
+	if state.Status == models.PresenceOffline {
+		delete(t.chats[chatID], userID)
+		if len(t.chats[chatID]) == 0 {
+			delete(t.chats, chatID)
+		}
+	}
+}
+
+// send queues an event for the cluster. Changes are dropped rather than
+// blocking when the cluster cannot keep up; present users are repeated anyway.
+func (t *Tracker) send(event events.Event) {
+	select {
+	case t.outbox <- event:
+	default:
+		slog.Warn("Dropping presence change for the cluster", "chatID", event.ChatID)
+	}
+}
@@ -0,0 +1,165 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(event events.Event) {
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) states() []models.Presence {
+	states := make([]models.Presence, 0, len(p.events))
+	for _, event := range p.events {
+		states = append(states, event.Data.(models.Presence))
+	}
+	return states
+}
+
+// sent returns the states queued for the cluster since the last call.
+func sent(tracker *Tracker) []models.Presence {
+	states := []models.Presence{}
+	for {
+		select {
+		case event := <-tracker.outbox:
+			states = append(states, event.Data.(models.Presence))
+		default:
+			return states
+		}
+	}
+}
+
+func newTestTracker() (*Tracker, *recordingPublisher, *time.Time) {
+	publisher := &recordingPublisher{}
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker := NewTracker(publisher, &recordingPublisher{}, 30*time.Second, 5*time.Minute)
+	tracker.now = func() time.Time { return clock }
+
+	return tracker, publisher, &clock
+}
+
+func TestTracker_ConnectionLifecycle(t *testing.T) {
+	// Arrange
+	tracker, publisher, clock := newTestTracker()
+
+	// Act
+	disconnect := tracker.Connect(1, "alice")
+	listed := tracker.List(1)
+
+	*clock = clock.Add(6 * time.Minute)
+	tracker.sweep()
+
+	disconnect()
+	disconnect()
+
+	// Assert
+	assert.Equal(t, []models.Presence{{UserID: "alice", Status: models.PresenceOnline}}, listed)
+	assert.Equal(t, []models.Presence{
+		{UserID: "alice", Status: models.PresenceOnline},
+		{UserID: "alice", Status: models.PresenceAway},
+		{UserID: "alice", Status: models.PresenceOffline},
+	}, publisher.states())
+	assert.Empty(t, tracker.List(1))
+}
+
+func TestTracker_TypingHeartbeatExpires(t *testing.T) {
+	// Arrange
+	tracker, publisher, clock := newTestTracker()
+
+	// Act
+	tracker.Typing(1, "bob")
+	tracker.Typing(1, "bob")
+
+	*clock = clock.Add(typingTTL)
+	tracker.sweep()
+	listed := tracker.List(1)
+
+	*clock = clock.Add(30 * time.Second)
+	tracker.sweep()
+
+	// Assert
+	assert.Equal(t, []models.Presence{{UserID: "bob", Status: models.PresenceOnline}}, listed)
+	assert.Equal(t, []models.Presence{
+		{UserID: "bob", Status: models.PresenceOnline, Typing: true},
+		{UserID: "bob", Status: models.PresenceOnline},
+		{UserID: "bob", Status: models.PresenceOffline},
+	}, publisher.states())
+	assert.Empty(t, tracker.List(1))
+}
+
+func TestTracker_ChatsAreIndependent(t *testing.T) {
+	// Arrange
+	tracker, publisher, _ := newTestTracker()
+
+	// Act
+	tracker.Connect(1, "alice")
+	tracker.Typing(2, "bob")
+
+	// Assert
+	assert.Len(t, tracker.List(1), 1)
+	assert.Equal(t, "bob", tracker.List(2)[0].UserID)
+	assert.Equal(t, 1, publisher.events[0].ChatID)
+	assert.Equal(t, 2, publisher.events[1].ChatID)
+}
+
+func TestTracker_RepeatsLocalStateToCluster(t *testing.T) {
+	// Arrange
+	tracker, _, clock := newTestTracker()
+
+	// Act
+	disconnect := tracker.Connect(1, "alice")
+	connected := sent(tracker)
+
+	*clock = clock.Add(5 * time.Second)
+	tracker.sweep()
+	early := sent(tracker)
+
+	*clock = clock.Add(5 * time.Second)
+	tracker.sweep()
+	repeated := sent(tracker)
+
+	disconnect()
+
+	// Assert
+	online := models.Presence{UserID: "alice", Status: models.PresenceOnline}
+	assert.Equal(t, []models.Presence{online}, connected)
+	assert.Empty(t, early)
+	assert.Equal(t, []models.Presence{online}, repeated)
+	assert.Equal(t, []models.Presence{{UserID: "alice", Status: models.PresenceOffline}}, sent(tracker))
+}
+
+func TestTracker_MergesRemoteStates(t *testing.T) {
+	// Arrange
+	tracker, publisher, clock := newTestTracker()
+
+	// Act
+	tracker.ApplyRemote("b", 1, models.Presence{UserID: "alice", Status: models.PresenceAway})
+	tracker.ApplyRemote("c", 1, models.Presence{UserID: "alice", Status: models.PresenceOnline, Typing: true})
+	listed := tracker.List(1)
+
+	tracker.ApplyRemote("c", 1, models.Presence{UserID: "alice", Status: models.PresenceOffline})
+
+	*clock = clock.Add(30 * time.Second)
+	tracker.sweep()
+
+	// Assert
+	assert.Equal(t, []models.Presence{{UserID: "alice", Status: models.PresenceOnline, Typing: true}}, listed)
+	assert.Equal(t, []models.Presence{
+		{UserID: "alice", Status: models.PresenceAway},
+		{UserID: "alice", Status: models.PresenceOnline, Typing: true},
+		{UserID: "alice", Status: models.PresenceAway},
+		{UserID: "alice", Status: models.PresenceOffline},
+	}, publisher.states())
+	assert.Empty(t, tracker.List(1))
+	assert.Empty(t, sent(tracker))
+}
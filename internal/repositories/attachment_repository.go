@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetByID(ctx context.Context, chatID int, id int, userID string) (*models.Attachment, error)
+	ClaimPendingThumbnails(ctx context.Context, limit int, lease time.Duration) ([]models.Attachment, error)
+	SaveThumbnail(ctx context.Context, attachment *models.Attachment) error
+	MarkThumbnailFailed(ctx context.Context, id int) error
+	DeleteUnlinked(ctx context.Context, createdBefore time.Time, limit int) (int64, error)
+	ListOrphanedBlobs(ctx context.Context, limit int) ([]string, error)
+	ForgetOrphanedBlobs(ctx context.Context, keys []string) error
+}
+
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+func (repo *attachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Chat{}).Where("id = ?", attachment.ChatID).Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check chat existence: %w", err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("chat with id %d not found", attachment.ChatID)
+		}
+
+		if err := tx.Create(attachment).Error; err != nil {
+			return fmt.Errorf("failed to create attachment: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetByID returns an attachment of a chat as seen by userID. Until it is linked
+// to a message, an attachment is only visible to its uploader. Attachments of
+// expired messages are not found even before the retention reaper deletes
+// them.
+func (repo *attachmentRepository) GetByID(ctx context.Context, chatID int, id int, userID string) (*models.Attachment, error) {
+	now := time.Now()
+
+	var attachment models.Attachment
+	err := repo.db.WithContext(ctx).
+		Where("id = ? AND chat_id = ?", id, chatID).
+		Where("(message_id IS NULL AND uploader_id = ?) OR EXISTS (SELECT 1 FROM messages m WHERE m.id = attachments.message_id AND "+
+			unexpiredCondition("m")+")", userID, now, now).
+		Take(&attachment).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("attachment with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// ClaimPendingThumbnails claims the oldest attachments waiting for a thumbnail
+// for the duration of lease. Attachments claimed by another worker are skipped
+// until their claim expires, so concurrent workers get distinct attachments.
+func (repo *attachmentRepository) ClaimPendingThumbnails(ctx context.Context, limit int, lease time.Duration) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("thumbnail_status = ?", models.ThumbnailPending).
+			Where("thumbnail_claimed_until IS NULL OR thumbnail_claimed_until <= ?", now).
+			Order("id ASC").
+			Limit(limit).
+			Find(&attachments).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to claim pending thumbnails: %w", err)
+		}
+
+		if len(attachments) == 0 {
+			return nil
+		}
+
+		ids := make([]int, len(attachments))
+		claimedUntil := now.Add(lease)
+		for i := range attachments {
+			ids[i] = attachments[i].ID
+			attachments[i].ThumbnailClaimedUntil = &claimedUntil
+		}
+
+		err = tx.
+			Model(&models.Attachment{}).
+			Where("id IN ?", ids).
+			Update("thumbnail_claimed_until", claimedUntil).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to claim pending thumbnails: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// SaveThumbnail records the dimensions and the thumbnail of a pending
+// attachment. It fails if the attachment has been deleted or processed in the
+// meantime, so that the caller can discard the thumbnail.
+func (repo *attachmentRepository) SaveThumbnail(ctx context.Context, attachment *models.Attachment) error {
+	result := repo.db.WithContext(ctx).
+		Model(&models.Attachment{}).
+		Where("id = ? AND thumbnail_status = ?", attachment.ID, models.ThumbnailPending).
+		Updates(map[string]interface{}{
+			"width":            attachment.Width,
+			"height":           attachment.Height,
+			"thumbnail_key":    attachment.ThumbnailKey,
+			"thumbnail_status": models.ThumbnailReady,
+		})
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("attachment with id %d is no longer pending", attachment.ID)
+	}
+
+	return nil
+}
+
+func (repo *attachmentRepository) MarkThumbnailFailed(ctx context.Context, id int) error {
+	err := repo.db.WithContext(ctx).
+		Model(&models.Attachment{}).
+		Where("id = ? AND thumbnail_status = ?", id, models.ThumbnailPending).
+		Update("thumbnail_status", models.ThumbnailFailed).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to mark thumbnail as failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUnlinked deletes up to limit uploads created before createdBefore
+// that were never attached to a message, and returns their number. Their
+// blobs are queued as orphaned.
+func (repo *attachmentRepository) DeleteUnlinked(ctx context.Context, createdBefore time.Time, limit int) (int64, error) {
+	db := repo.db.WithContext(ctx)
+
+	unlinked := db.
+		Model(&models.Attachment{}).
+		Select("id").
+		Where("message_id IS NULL AND created_at < ?", createdBefore).
+		Limit(limit)
+
+	result := db.Where("id IN (?) AND message_id IS NULL", unlinked).Delete(&models.Attachment{})
+	if err := result.Error; err != nil {
+		return 0, fmt.Errorf("failed to delete unlinked attachments: %w", err)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// ListOrphanedBlobs returns the keys of up to limit blobs of deleted
+// attachments, oldest first.
+func (repo *attachmentRepository) ListOrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	keys := []string{}
+	err := repo.db.WithContext(ctx).
+		Model(&models.OrphanedBlob{}).
+		Order("created_at ASC, storage_key ASC").
+		Limit(limit).
+		Pluck("storage_key", &keys).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orphaned blobs: %w", err)
+	}
+
+	return keys, nil
+}
+
+// ForgetOrphanedBlobs removes blobs deleted from the blob store from the
+// queue.
+func (repo *attachmentRepository) ForgetOrphanedBlobs(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := repo.db.WithContext(ctx).Where("storage_key IN ?", keys).Delete(&models.OrphanedBlob{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to forget orphaned blobs: %w", err)
+	}
+
+	return nil
+}
+
+// linkAttachments attaches the uploads listed in message.Attachments to the
+// newly created message and loads them. Only unlinked uploads of the author
+// in the same chat may be attached.
+func linkAttachments(tx *gorm.DB, message *models.Message) error {
+	ids := make([]int, 0, len(message.Attachments))
+	for _, attachment := range message.Attachments {
+		ids = append(ids, attachment.ID)
+	}
+
+	result := tx.Model(&models.Attachment{}).
+		Where("id IN ? AND chat_id = ? AND message_id IS NULL", ids, message.ChatID).
+		Where("uploader_id = ?", message.AuthorID).
+		Update("message_id", message.ID)
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to link attachments: %w", err)
+	}
+
+	if result.RowsAffected != int64(len(ids)) {
+		return fmt.Errorf("attachments %v are not available to message %d", ids, message.ID)
+	}
+
+	message.Attachments = nil
+	return tx.Scopes(orderedAttachments).Where("message_id = ?", message.ID).Find(&message.Attachments).Error
+}
+
+func orderedAttachments(db *gorm.DB) *gorm.DB {
+	return db.Order("id ASC")
+}
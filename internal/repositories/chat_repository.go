@@ -4,17 +4,39 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jonx8/chat-service/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ChatRepository interface {
-	GetByID(ctx context.Context, id int, limit int) (*models.Chat, error)
+	GetByID(ctx context.Context, id int, page MessagePage) (*models.Chat, error)
 	CreateIfNotExists(ctx context.Context, chat *models.Chat) error
+	GetOrCreateDirect(ctx context.Context, user *models.User, peer *models.User) (*models.Chat, bool, error)
 	DeleteByID(ctx context.Context, id int) error
+	UpdateRetention(ctx context.Context, id int, retention models.Retention) error
+	List(ctx context.Context, params ChatListParams) ([]models.ChatSummary, int64, error)
+	AdoptOrphaned(ctx context.Context, owner *models.User) (int64, error)
 }
 
+// ChatListParams filters and orders a chat listing. Chats are always returned
+// newest first, either by creation time or by the time of the last message.
+// Only the chats MemberID belongs to are listed.
+type ChatListParams struct {
+	MemberID        string
+	Limit           int
+	Offset          int
+	TitlePrefix     string
+	OrderByActivity bool
+}
+
+// previewLength is the number of characters of the last message included in
+// chat listings.
+const previewLength = 100
+
 type chatRepository struct {
 	db *gorm.DB
 }
@@ -29,7 +51,7 @@ func (repo *chatRepository) CreateIfNotExists(ctx context.Context, chat *models.
 
 		err := tx.
 			Model(&models.Chat{}).
-			Where("title = ?", chat.Title).
+			Where("title = ? AND kind = ?", chat.Title, models.ChatKindGroup).
 			Count(&count).Error
 
 		if err != nil {
@@ -40,30 +62,136 @@ func (repo *chatRepository) CreateIfNotExists(ctx context.Context, chat *models.
 			return fmt.Errorf("chat with title '%s' already exists", chat.Title)
 		}
 
-		err = tx.Create(chat).Error
+		if chat.Owner != nil {
+			if err := upsertUser(tx, chat.Owner); err != nil {
+				return err
+			}
+			chat.OwnerID = &chat.Owner.ID
+		}
+
+		err = tx.Omit(clause.Associations).Create(chat).Error
 		if chat.Messages == nil {
 			chat.Messages = []models.Message{}
 		}
+		if chat.Pinned == nil {
+			chat.Pinned = []models.PinnedMessage{}
+		}
 
-		return err
+		if err != nil || chat.OwnerID == nil {
+			return err
+		}
 
+		return addMember(tx, chat.ID, *chat.OwnerID, models.RoleOwner)
 	})
 }
 
-func (repo *chatRepository) GetByID(ctx context.Context, id int, limit int) (*models.Chat, error) {
+// GetOrCreateDirect returns the direct chat between two users, creating it on
+// first use. Both users are (re)added as members, so a user who left the chat
+// gets back in by opening it again. The boolean reports whether the chat was
+// created.
+func (repo *chatRepository) GetOrCreateDirect(ctx context.Context, user *models.User, peer *models.User) (*models.Chat, bool, error) {
+	// Go compares strings bytewise, which is the order of the "C" collation
+	// used by chk_chats_direct_pair.
+	low, high := user.ID, peer.ID
+	if high < low {
+		low, high = high, low
+	}
 
-	tx := repo.db.WithContext(ctx).Model(&models.Chat{})
+	chat := models.Chat{
+		Kind:             models.ChatKindDirect,
+		DirectLowUserID:  &low,
+		DirectHighUserID: &high,
+		Messages:         []models.Message{},
+		Pinned:           []models.PinnedMessage{},
+	}
+	created := false
 
-	if limit > 0 {
-		tx = tx.Preload("Messages", func(db *gorm.DB) *gorm.DB {
-			return db.Order("created_at DESC").Limit(limit)
-		})
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, u := range []*models.User{user, peer} {
+			if err := upsertUser(tx, u); err != nil {
+				return err
+			}
+		}
+
+		// A concurrent request for the same pair waits on the unique index
+		// and then sees the row committed by the other transaction. The
+		// predicate is inlined because Postgres cannot match a partial index
+		// against a bound parameter.
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "direct_low_user_id"}, {Name: "direct_high_user_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{
+				gorm.Expr("kind = 'direct'"),
+			}},
+			DoNothing: true,
+		}).Omit(clause.Associations).Create(&chat)
+
+		if err := result.Error; err != nil {
+			return fmt.Errorf("failed to create direct chat: %w", err)
+		}
+
+		created = result.RowsAffected > 0
+		if !created {
+			err := tx.
+				Where("kind = ? AND direct_low_user_id = ? AND direct_high_user_id = ?", models.ChatKindDirect, low, high).
+				Take(&chat).Error
+
+			if err != nil {
+				return fmt.Errorf("failed to get direct chat: %w", err)
+			}
+		}
+
+		for _, u := range []*models.User{user, peer} {
+			if err := addMember(tx, chat.ID, u.ID, models.RoleMember); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &chat, created, nil
+}
+
+// GetByID returns a chat with its pins and, when page.Limit is set, a window of
+// its messages. Expired messages are never included.
+func (repo *chatRepository) GetByID(ctx context.Context, id int, page MessagePage) (*models.Chat, error) {
+	now := time.Now()
+
+	pinnable := repo.db.WithContext(ctx).
+		Model(&models.Message{}).
+		Scopes(unexpiredMessages(now)).
+		Select("id").
+		Where("chat_id = ?", id)
+
+	tx := repo.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Preload("Owner").
+		Preload("Pinned", func(db *gorm.DB) *gorm.DB {
+			return db.
+				Where("message_id IN (?)", pinnable).
+				Order("pinned_at DESC, message_id DESC")
+		}).
+		Preload("Pinned.Message").
+		Preload("Pinned.Message.Author")
+
+	if page.Limit > 0 {
+		tx = tx.
+			Preload("Messages", messagePageScope(page), rootMessages, unexpiredMessages(now)).
+			Preload("Messages.Author").
+			Preload("Messages.Attachments", orderedAttachments)
 	}
 
 	var chat models.Chat
 	if chat.Messages == nil {
 		chat.Messages = []models.Message{}
 	}
+	if chat.Pinned == nil {
+		chat.Pinned = []models.PinnedMessage{}
+	}
 
 	if err := tx.First(&chat, id).Error; err != nil {
 
@@ -73,6 +201,12 @@ func (repo *chatRepository) GetByID(ctx context.Context, id int, limit int) (*mo
 		return nil, fmt.Errorf("failed to get chat: %w", err)
 	}
 
+	if err := attachReplyStats(repo.db.WithContext(ctx), chat.Messages); err != nil {
+		return nil, err
+	}
+
+	chat.Messages = orderNewestFirst(chat.Messages, page)
+
 	return &chat, nil
 }
 
@@ -88,3 +222,186 @@ func (repo *chatRepository) DeleteByID(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// UpdateRetention replaces the retention policy of a chat.
+func (repo *chatRepository) UpdateRetention(ctx context.Context, id int, retention models.Retention) error {
+	result := repo.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"retention_max_age":      retention.MaxAge,
+			"retention_max_messages": retention.MaxMessages,
+		})
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to update retention: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("chat with id %d not found", id)
+	}
+
+	return nil
+}
+
+// AdoptOrphaned makes owner the owner of every group chat that has no owner
+// among its members, adding them as a member where necessary. It returns the
+// number of chats adopted.
+func (repo *chatRepository) AdoptOrphaned(ctx context.Context, owner *models.User) (int64, error) {
+	var ids []int
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&models.Chat{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("kind = ?", models.ChatKindGroup).
+			Where("NOT EXISTS (SELECT 1 FROM chat_members cm WHERE cm.chat_id = chats.id AND cm.role = ?)", models.RoleOwner).
+			Pluck("id", &ids).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to find orphaned chats: %w", err)
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := upsertUser(tx, owner); err != nil {
+			return err
+		}
+
+		members := make([]models.ChatMember, 0, len(ids))
+		for _, id := range ids {
+			members = append(members, models.ChatMember{ChatID: id, UserID: owner.ID, Role: models.RoleOwner})
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"role": models.RoleOwner}),
+		}).Omit(clause.Associations).Create(&members).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to add owners: %w", err)
+		}
+
+		err = tx.
+			Model(&models.Chat{}).
+			Where("id IN ?", ids).
+			Update("owner_id", owner.ID).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to set owners: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(ids)), nil
+}
+
+type chatSummaryRow struct {
+	ID              int
+	Kind            string
+	Title           string
+	CreatedAt       time.Time
+	LastActivityAt  time.Time
+	MessageCount    int64
+	UnreadCount     int64
+	LastMessageID   *int
+	LastMessageText *string
+	LastMessageAt   *time.Time
+}
+
+func (repo *chatRepository) List(ctx context.Context, params ChatListParams) ([]models.ChatSummary, int64, error) {
+	tx := repo.db.WithContext(ctx).
+		Table("chats AS c").
+		Joins("JOIN chat_members cm ON cm.chat_id = c.id AND cm.user_id = ?", params.MemberID)
+
+	if params.TitlePrefix != "" {
+		tx = tx.Where("c.title ILIKE ? ESCAPE '\\'", escapeLike(params.TitlePrefix)+"%")
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count chats: %w", err)
+	}
+
+	// The retention cutoffs of the listed chats are joined once and shared by
+	// the subqueries below.
+	now := time.Now()
+	retained := "(m.expires_at IS NULL OR m.expires_at > ?) AND " + retentionExpired("m") + " IS NOT TRUE"
+
+	order := "c.created_at DESC, c.id DESC"
+	if params.OrderByActivity {
+		order = "last_activity_at DESC, c.id DESC"
+	}
+
+	var rows []chatSummaryRow
+	err := tx.
+		Select(`c.id, c.kind, c.title, c.created_at,
+			COALESCE(lm.created_at, c.created_at) AS last_activity_at,
+			mc.message_count,
+			uc.unread_count,
+			lm.id AS last_message_id,
+			LEFT(lm.text, ?) AS last_message_text,
+			lm.created_at AS last_message_at`, previewLength).
+		Joins("LEFT JOIN "+retentionCutoffs+" rc ON rc.chat_id = c.id", now).
+		Joins(`CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS message_count FROM messages m
+			WHERE m.chat_id = c.id AND m.deleted_at IS NULL AND `+retained+`
+		) mc`, now).
+		Joins(`CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS unread_count FROM messages m
+			WHERE `+unreadCondition+` AND `+retained+`
+		) uc`, now).
+		Joins(`LEFT JOIN LATERAL (
+			SELECT m.id, m.text, m.created_at FROM messages m
+			WHERE m.chat_id = c.id AND m.deleted_at IS NULL AND `+retained+`
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT 1
+		) lm ON TRUE`, now).
+		Order(order).
+		Limit(params.Limit).
+		Offset(params.Offset).
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	chats := make([]models.ChatSummary, 0, len(rows))
+	for _, row := range rows {
+		chat := models.ChatSummary{
+			ID:             row.ID,
+			Kind:           row.Kind,
+			Title:          row.Title,
+			CreatedAt:      row.CreatedAt,
+			LastActivityAt: row.LastActivityAt,
+			MessageCount:   row.MessageCount,
+			UnreadCount:    row.UnreadCount,
+		}
+
+		if row.LastMessageID != nil {
+			chat.LastMessage = &models.MessagePreview{
+				ID:        *row.LastMessageID,
+				Text:      *row.LastMessageText,
+				CreatedAt: *row.LastMessageAt,
+			}
+		}
+
+		chats = append(chats, chat)
+	}
+
+	return chats, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key *models.IdempotencyKey, expiredBefore time.Time, staleBefore time.Time) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, key *models.IdempotencyKey) error
+	Release(ctx context.Context, key *models.IdempotencyKey) error
+	DeleteExpired(ctx context.Context, expiredBefore time.Time, limit int) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve stores a new key unless the caller already uses it. It returns nil
+// when the key has been reserved and the stored key otherwise. Keys created
+// before expiredBefore, and keys still without a response created before
+// staleBefore, are taken over as if they did not exist.
+func (repo *idempotencyRepository) Reserve(ctx context.Context, key *models.IdempotencyKey, expiredBefore time.Time, staleBefore time.Time) (*models.IdempotencyKey, error) {
+	db := repo.db.WithContext(ctx)
+
+	key.CreatedAt = time.Now()
+	result := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"fingerprint":  gorm.Expr("EXCLUDED.fingerprint"),
+			"status_code":  nil,
+			"content_type": nil,
+			"body":         nil,
+			"created_at":   gorm.Expr("EXCLUDED.created_at"),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("idempotency_keys.created_at <= ? OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= ?)", expiredBefore, staleBefore),
+		}},
+	}).Create(key)
+
+	if err := result.Error; err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	err := db.Where("user_id = ? AND key = ?", key.UserID, key.Key).Take(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("idempotency key %s was released concurrently", key.Key)
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &existing, nil
+}
+
+// Complete stores the response of a reserved key.
+func (repo *idempotencyRepository) Complete(ctx context.Context, key *models.IdempotencyKey) error {
+	result := repo.db.WithContext(ctx).
+		Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND key = ? AND fingerprint = ? AND status_code IS NULL", key.UserID, key.Key, key.Fingerprint).
+		Updates(map[string]interface{}{
+			"status_code":  key.StatusCode,
+			"content_type": key.ContentType,
+			"body":         key.Body,
+		})
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("idempotency key %s is no longer reserved", key.Key)
+	}
+
+	return nil
+}
+
+// Release deletes a reserved key that has no response, so that the request
+// can be retried.
+func (repo *idempotencyRepository) Release(ctx context.Context, key *models.IdempotencyKey) error {
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ? AND key = ? AND fingerprint = ? AND status_code IS NULL", key.UserID, key.Key, key.Fingerprint).
+		Delete(&models.IdempotencyKey{}).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes up to limit keys created before expiredBefore and
+// returns the number of deleted keys.
+func (repo *idempotencyRepository) DeleteExpired(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	db := repo.db.WithContext(ctx)
+
+	expired := db.
+		Model(&models.IdempotencyKey{}).
+		Select("user_id, key").
+		Where("created_at <= ?", expiredBefore).
+		Limit(limit)
+
+	result := db.Where("(user_id, key) IN (?)", expired).Delete(&models.IdempotencyKey{})
+	if err := result.Error; err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteRepository interface {
+	CreateInvite(ctx context.Context, invite *models.ChatInvite) error
+	ListInvites(ctx context.Context, chatID int) ([]models.ChatInvite, error)
+	RevokeInvite(ctx context.Context, chatID int, inviteID int) error
+	AcceptInvite(ctx context.Context, tokenHash string, user *models.User) (*models.ChatMember, error)
+}
+
+type inviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) InviteRepository {
+	return &inviteRepository{db: db}
+}
+
+func (repo *inviteRepository) CreateInvite(ctx context.Context, invite *models.ChatInvite) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Chat{}).Where("id = ?", invite.ChatID).Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check chat existence: %w", err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("chat with id %d not found", invite.ChatID)
+		}
+
+		return tx.Create(invite).Error
+	})
+}
+
+func (repo *inviteRepository) ListInvites(ctx context.Context, chatID int) ([]models.ChatInvite, error) {
+	invites := []models.ChatInvite{}
+	err := repo.db.WithContext(ctx).
+		Where("chat_id = ?", chatID).
+		Order("id DESC").
+		Find(&invites).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+func (repo *inviteRepository) RevokeInvite(ctx context.Context, chatID int, inviteID int) error {
+	result := repo.db.WithContext(ctx).
+		Model(&models.ChatInvite{}).
+		Where("id = ? AND chat_id = ? AND revoked_at IS NULL", inviteID, chatID).
+		Update("revoked_at", time.Now())
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("invite with id %d not found", inviteID)
+	}
+
+	return nil
+}
+
+// AcceptInvite makes user a member of the invite's chat and counts the use.
+// The invite row stays locked until the end of the transaction so that
+// concurrent acceptances cannot exceed max_uses.
+func (repo *inviteRepository) AcceptInvite(ctx context.Context, tokenHash string, user *models.User) (*models.ChatMember, error) {
+	var member models.ChatMember
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invite models.ChatInvite
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			Take(&invite).Error
+
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invite not found")
+			}
+			return fmt.Errorf("failed to get invite: %w", err)
+		}
+
+		switch {
+		case invite.RevokedAt != nil:
+			return fmt.Errorf("invite %d is no longer valid: revoked", invite.ID)
+		case invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now()):
+			return fmt.Errorf("invite %d is no longer valid: expired", invite.ID)
+		case invite.MaxUses != nil && invite.Uses >= *invite.MaxUses:
+			return fmt.Errorf("invite %d is no longer valid: used %d times", invite.ID, invite.Uses)
+		}
+
+		var count int64
+		err = tx.Model(&models.ChatMember{}).
+			Where("chat_id = ? AND user_id = ?", invite.ChatID, user.ID).
+			Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check membership: %w", err)
+		}
+
+		if count > 0 {
+			return fmt.Errorf("user %s is already a member of chat %d", user.ID, invite.ChatID)
+		}
+
+		if err := upsertUser(tx, user); err != nil {
+			return err
+		}
+
+		member = models.ChatMember{ChatID: invite.ChatID, UserID: user.ID, Role: models.RoleMember, User: user}
+		if err := tx.Omit(clause.Associations).Create(&member).Error; err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+
+		err = tx.Model(&invite).Update("uses", gorm.Expr("uses + 1")).Error
+		if err != nil {
+			return fmt.Errorf("failed to count invite use: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MemberRepository interface {
+	GetMember(ctx context.Context, chatID int, userID string) (*models.ChatMember, error)
+	ListMembers(ctx context.Context, chatID int) ([]models.ChatMember, error)
+	AddMember(ctx context.Context, member *models.ChatMember) error
+	RemoveMember(ctx context.Context, chatID int, userID string) error
+	UpdateRole(ctx context.Context, chatID int, userID string, role string) (*models.ChatMember, error)
+	TransferOwnership(ctx context.Context, chatID int, fromUserID string, toUserID string) (*models.ChatMember, error)
+	MarkRead(ctx context.Context, chatID int, userID string, messageID int) (*models.ChatMember, error)
+	CountUnread(ctx context.Context, chatID int, userID string) (int64, error)
+}
+
+// unreadCondition matches the messages m that member cm has not read yet: the
+// messages of others written after the read position, which is NULL when the
+// member has read nothing. The (created_at, id) comparison is served by
+// idx_messages_chat_id_created_at.
+const unreadCondition = `m.chat_id = cm.chat_id AND m.deleted_at IS NULL
+	AND m.author_id IS DISTINCT FROM cm.user_id
+	AND (cm.last_read_at IS NULL
+		OR (m.created_at, m.id) > (cm.last_read_at, cm.last_read_message_id))`
+
+type memberRepository struct {
+	db *gorm.DB
+}
+
+func NewMemberRepository(db *gorm.DB) MemberRepository {
+	return &memberRepository{db: db}
+}
+
+// GetMember returns the membership of a user. When there is none, the error
+// tells apart a missing chat from a user who is not a member. The chat is
+// loaded with the membership, since permissions depend on its kind.
+func (repo *memberRepository) GetMember(ctx context.Context, chatID int, userID string) (*models.ChatMember, error) {
+	db := repo.db.WithContext(ctx)
+
+	var member models.ChatMember
+	err := db.Preload("Chat").Where("chat_id = ? AND user_id = ?", chatID, userID).Take(&member).Error
+	if err == nil {
+		return &member, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+
+	var count int64
+	if err := db.Model(&models.Chat{}).Where("id = ?", chatID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("check chat existence: %w", err)
+	}
+
+	if count == 0 {
+		return nil, fmt.Errorf("chat with id %d not found", chatID)
+	}
+
+	return nil, fmt.Errorf("user %s is not a member of chat %d", userID, chatID)
+}
+
+func (repo *memberRepository) ListMembers(ctx context.Context, chatID int) ([]models.ChatMember, error) {
+	members := []models.ChatMember{}
+	err := repo.db.WithContext(ctx).
+		Preload("User").
+		Where("chat_id = ?", chatID).
+		Order("joined_at ASC, user_id ASC").
+		Find(&members).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember adds a user to a chat. Users who have never called the service
+// yet get a placeholder record that is filled in on their first request.
+func (repo *memberRepository) AddMember(ctx context.Context, member *models.ChatMember) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Chat{}).Where("id = ?", member.ChatID).Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check chat existence: %w", err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("chat with id %d not found", member.ChatID)
+		}
+
+		err = tx.Model(&models.ChatMember{}).
+			Where("chat_id = ? AND user_id = ?", member.ChatID, member.UserID).
+			Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check membership: %w", err)
+		}
+
+		if count > 0 {
+			return fmt.Errorf("user %s is already a member of chat %d", member.UserID, member.ChatID)
+		}
+
+		member.User = &models.User{ID: member.UserID}
+		if err := upsertUser(tx, member.User); err != nil {
+			return err
+		}
+
+		return tx.Omit(clause.Associations).Create(member).Error
+	})
+}
+
+func (repo *memberRepository) RemoveMember(ctx context.Context, chatID int, userID string) error {
+	result := repo.db.WithContext(ctx).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Delete(&models.ChatMember{})
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("member %s of chat %d not found", userID, chatID)
+	}
+
+	return nil
+}
+
+// UpdateRole changes the role of a member other than the owner. Ownership
+// only changes hands through TransferOwnership.
+func (repo *memberRepository) UpdateRole(ctx context.Context, chatID int, userID string, role string) (*models.ChatMember, error) {
+	var member models.ChatMember
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ChatMember{}).
+			Where("chat_id = ? AND user_id = ? AND role <> ?", chatID, userID, models.RoleOwner).
+			Update("role", role)
+
+		if err := result.Error; err != nil {
+			return fmt.Errorf("failed to update member role: %w", err)
+		}
+
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("member %s of chat %d not found", userID, chatID)
+		}
+
+		err := tx.Preload("User").Where("chat_id = ? AND user_id = ?", chatID, userID).Take(&member).Error
+		if err != nil {
+			return fmt.Errorf("failed to get member: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// TransferOwnership makes toUserID the owner of a chat and demotes the current
+// owner to admin. Both memberships are locked so that concurrent transfers or
+// role changes cannot leave the chat with zero or two owners.
+func (repo *memberRepository) TransferOwnership(ctx context.Context, chatID int, fromUserID string, toUserID string) (*models.ChatMember, error) {
+	var owner models.ChatMember
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var members []models.ChatMember
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("chat_id = ? AND user_id IN ?", chatID, []string{fromUserID, toUserID}).
+			Order("user_id").
+			Find(&members).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to lock members: %w", err)
+		}
+
+		var from, to *models.ChatMember
+		for i := range members {
+			switch members[i].UserID {
+			case fromUserID:
+				from = &members[i]
+			case toUserID:
+				to = &members[i]
+			}
+		}
+
+		if from == nil || from.Role != models.RoleOwner {
+			return fmt.Errorf("user %s is not the owner of chat %d", fromUserID, chatID)
+		}
+
+		if to == nil {
+			return fmt.Errorf("member %s of chat %d not found", toUserID, chatID)
+		}
+
+		// The old owner is demoted first to keep idx_chat_members_owner satisfied.
+		if err := tx.Model(from).Update("role", models.RoleAdmin).Error; err != nil {
+			return fmt.Errorf("failed to demote owner: %w", err)
+		}
+
+		if err := tx.Model(to).Update("role", models.RoleOwner).Error; err != nil {
+			return fmt.Errorf("failed to promote owner: %w", err)
+		}
+
+		err = tx.Model(&models.Chat{}).Where("id = ?", chatID).Update("owner_id", toUserID).Error
+		if err != nil {
+			return fmt.Errorf("failed to update chat owner: %w", err)
+		}
+
+		err = tx.Preload("User").Where("chat_id = ? AND user_id = ?", chatID, toUserID).Take(&owner).Error
+		if err != nil {
+			return fmt.Errorf("failed to get member: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &owner, nil
+}
+
+// MarkRead moves the read position of a member to a message of the chat, or to
+// the latest message when messageID is zero. The position never moves
+// backwards: marking an older message as read leaves it unchanged.
+func (repo *memberRepository) MarkRead(ctx context.Context, chatID int, userID string, messageID int) (*models.ChatMember, error) {
+	var member models.ChatMember
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var message models.Message
+		query := tx.Where("chat_id = ?", chatID)
+
+		if messageID == 0 {
+			query = query.Order("created_at DESC, id DESC")
+		} else {
+			query = query.Where("id = ?", messageID)
+		}
+
+		err := query.Take(&message).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound) && messageID != 0:
+			return fmt.Errorf("message with id %d not found", messageID)
+		case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+			return fmt.Errorf("failed to get message: %w", err)
+		case err == nil:
+			err = tx.Model(&models.ChatMember{}).
+				Where("chat_id = ? AND user_id = ?", chatID, userID).
+				Where("last_read_at IS NULL OR (?::timestamptz, ?::int) > (last_read_at, last_read_message_id)",
+					message.CreatedAt, message.ID).
+				Updates(map[string]interface{}{
+					"last_read_message_id": message.ID,
+					"last_read_at":         message.CreatedAt,
+				}).Error
+
+			if err != nil {
+				return fmt.Errorf("failed to update read position: %w", err)
+			}
+		}
+
+		err = tx.Where("chat_id = ? AND user_id = ?", chatID, userID).Take(&member).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("user %s is not a member of chat %d", userID, chatID)
+			}
+			return fmt.Errorf("failed to get member: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (repo *memberRepository) CountUnread(ctx context.Context, chatID int, userID string) (int64, error) {
+	now := time.Now()
+
+	var count int64
+	err := repo.db.WithContext(ctx).
+		Table("chat_members AS cm").
+		Joins("JOIN messages m ON "+unreadCondition+" AND "+unexpiredCondition("m"), now, now).
+		Where("cm.chat_id = ? AND cm.user_id = ?", chatID, userID).
+		Count(&count).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+
+	return count, nil
+}
+
+// addMember makes user a member of chat within an open transaction.
+func addMember(tx *gorm.DB, chatID int, userID string, role string) error {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Omit(clause.Associations).
+		Create(&models.ChatMember{ChatID: chatID, UserID: userID, Role: role}).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+
+	return nil
+}
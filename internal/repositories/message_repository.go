@@ -2,14 +2,40 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/jonx8/chat-service/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// MessageCursor identifies a position in a chat's message history.
+type MessageCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// MessagePage describes a keyset window over a chat's messages. Messages are
+// always returned newest first, regardless of the direction of the window.
+type MessagePage struct {
+	Limit  int
+	Before *MessageCursor
+	After  *MessageCursor
+}
+
 type MessageRepository interface {
-	CreateMessage(ctx context.Context, message *models.Message) error
+	CreateMessage(ctx context.Context, message *models.Message) (bool, error)
+	GetByID(ctx context.Context, id int) (*models.Message, error)
+	ListByChat(ctx context.Context, chatID int, page MessagePage) ([]models.Message, error)
+	UpdateText(ctx context.Context, chatID int, messageID int, text string) (*models.Message, error)
+	SoftDelete(ctx context.Context, chatID int, messageID int) (*models.Message, error)
+	ListSince(ctx context.Context, chatID int, afterID int, limit int) ([]models.Message, error)
+	ListRevisions(ctx context.Context, chatID int, messageID int) ([]models.MessageRevision, error)
+	ListThread(ctx context.Context, chatID int, messageID int, page MessagePage) (*models.Message, []models.Message, error)
+	DeleteExpired(ctx context.Context, limit int) (int64, error)
 }
 
 type messageRepository struct {
@@ -20,8 +46,22 @@ func NewMessageRepository(db *gorm.DB) MessageRepository {
 	return &messageRepository{db: db}
 }
 
-func (repo *messageRepository) CreateMessage(ctx context.Context, message *models.Message) error {
-	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+// CreateMessage stores a message and reports whether it was created. When the
+// author has already sent a message with the same ClientID to the chat,
+// message is replaced with the stored one instead. A retry is answered with
+// the stored message before any validation, so that it succeeds even if, for
+// instance, the parent has been deleted since.
+func (repo *messageRepository) CreateMessage(ctx context.Context, message *models.Message) (bool, error) {
+	created := true
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if message.ClientID != nil && message.Author != nil {
+			found, err := findByClientID(tx, message, message.Author.ID)
+			if err != nil || found {
+				created = !found
+				return err
+			}
+		}
+
 		var count int64
 		err := tx.Model(&models.Chat{}).Where("id = ?", message.ChatID).Count(&count).Error
 
@@ -33,6 +73,552 @@ func (repo *messageRepository) CreateMessage(ctx context.Context, message *model
 			return fmt.Errorf("chat with id %d not found", message.ChatID)
 		}
 
-		return tx.Create(message).Error
+		if message.ParentID != nil {
+			if err := checkParent(tx, message); err != nil {
+				return err
+			}
+		}
+
+		if message.Author != nil {
+			if err := upsertUser(tx, message.Author); err != nil {
+				return err
+			}
+			message.AuthorID = &message.Author.ID
+		}
+
+		result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "chat_id"}, {Name: "author_id"}, {Name: "client_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{gorm.Expr("client_id IS NOT NULL")}},
+			DoNothing:   true,
+		}).Create(message)
+
+		if err := result.Error; err != nil {
+			return err
+		}
+
+		if result.RowsAffected == 0 {
+			created = false
+			found, err := findByClientID(tx, message, *message.AuthorID)
+			if err == nil && !found {
+				err = fmt.Errorf("message with client id %s was deleted concurrently", *message.ClientID)
+			}
+			return err
+		}
+
+		if len(message.Attachments) > 0 {
+			return linkAttachments(tx, message)
+		}
+
+		return nil
+	})
+
+	return created, err
+}
+
+// findByClientID replaces message with the message authorID already sent to
+// the chat with the same client id and reports whether there is one.
+func findByClientID(tx *gorm.DB, message *models.Message, authorID string) (bool, error) {
+	var existing models.Message
+	err := tx.
+		Preload("Author").
+		Preload("Attachments", orderedAttachments).
+		Where("chat_id = ? AND author_id = ? AND client_id = ?", message.ChatID, authorID, message.ClientID).
+		Take(&existing).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get message by client id: %w", err)
+	}
+
+	*message = existing
+	return true, nil
+}
+
+func (repo *messageRepository) GetByID(ctx context.Context, id int) (*models.Message, error) {
+	var message models.Message
+	if err := repo.db.WithContext(ctx).
+		Preload("Author").
+		Preload("Attachments", orderedAttachments).
+		Take(&message, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("message with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return &message, nil
+}
+
+func (repo *messageRepository) ListByChat(ctx context.Context, chatID int, page MessagePage) ([]models.Message, error) {
+	db := repo.db.WithContext(ctx)
+
+	var count int64
+	if err := db.Model(&models.Chat{}).Where("id = ?", chatID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("check chat existence: %w", err)
+	}
+
+	if count == 0 {
+		return nil, fmt.Errorf("chat with id %d not found", chatID)
+	}
+
+	messages := []models.Message{}
+	err := db.
+		Scopes(messagePageScope(page), rootMessages, unexpiredMessages(time.Now())).
+		Preload("Author").
+		Preload("Attachments", orderedAttachments).
+		Where("chat_id = ?", chatID).
+		Find(&messages).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	if err := attachReplyStats(db, messages); err != nil {
+		return nil, err
+	}
+
+	return orderNewestFirst(messages, page), nil
+}
+
+// ListThread returns a root message together with a window of its replies.
+func (repo *messageRepository) ListThread(ctx context.Context, chatID int, messageID int, page MessagePage) (*models.Message, []models.Message, error) {
+	db := repo.db.WithContext(ctx)
+
+	now := time.Now()
+
+	var root models.Message
+	err := db.
+		Scopes(rootMessages, unexpiredMessages(now)).
+		Preload("Author").
+		Preload("Attachments", orderedAttachments).
+		Where("id = ? AND chat_id = ?", messageID, chatID).
+		Take(&root).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("message with id %d not found", messageID)
+		}
+		return nil, nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	replies := []models.Message{}
+	err = db.
+		Scopes(messagePageScope(page), unexpiredMessages(now)).
+		Preload("Author").
+		Preload("Attachments", orderedAttachments).
+		Where("parent_id = ?", messageID).
+		Find(&replies).Error
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list replies: %w", err)
+	}
+
+	roots := []models.Message{root}
+	if err := attachReplyStats(db, roots); err != nil {
+		return nil, nil, err
+	}
+
+	return &roots[0], orderNewestFirst(replies, page), nil
+}
+
+func (repo *messageRepository) UpdateText(ctx context.Context, chatID int, messageID int, text string) (*models.Message, error) {
+	var message models.Message
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockActiveMessage(tx, chatID, messageID, &message); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := saveRevision(tx, &message, now); err != nil {
+			return err
+		}
+
+		err := tx.Model(&message).Updates(map[string]interface{}{
+			"text":      text,
+			"edited_at": now,
+		}).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to update message: %w", err)
+		}
+
+		message.Text = text
+		message.EditedAt = &now
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// SoftDelete replaces the message with a tombstone: the row is kept so that
+// the history stays consistent, but its text is erased. The erased text is
+// still retained as a revision. A deleted message is no longer pinned and its
+// attachments become unavailable.
+func (repo *messageRepository) SoftDelete(ctx context.Context, chatID int, messageID int) (*models.Message, error) {
+	var message models.Message
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockActiveMessage(tx, chatID, messageID, &message); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := saveRevision(tx, &message, now); err != nil {
+			return err
+		}
+
+		err := tx.Model(&message).Updates(map[string]interface{}{
+			"text":       "",
+			"deleted_at": now,
+		}).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to delete message: %w", err)
+		}
+
+		err = tx.Where("chat_id = ? AND message_id = ?", chatID, messageID).Delete(&models.PinnedMessage{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to unpin deleted message: %w", err)
+		}
+
+		err = tx.Where("message_id = ?", messageID).Delete(&models.Attachment{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete attachments: %w", err)
+		}
+
+		message.Text = ""
+		message.DeletedAt = &now
+
+		return nil
 	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// ListSince returns up to limit messages of a chat with an id greater than
+// afterID in ascending id order.
+func (repo *messageRepository) ListSince(ctx context.Context, chatID int, afterID int, limit int) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := repo.db.WithContext(ctx).
+		Scopes(unexpiredMessages(time.Now())).
+		Preload("Author").
+		Preload("Attachments", orderedAttachments).
+		Where("chat_id = ? AND id > ?", chatID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// DeleteExpired deletes up to limit messages past their expires_at and up to
+// limit messages beyond the retention policy of their chat, and returns the
+// number of deleted messages. Like SoftDelete, it leaves a tombstone without
+// text, pins or attachments behind, so that replies, edit history and read
+// positions referring to the message stay intact.
+func (repo *messageRepository) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	db := repo.db.WithContext(ctx)
+	now := time.Now()
+
+	var expired []int
+	err := db.
+		Model(&models.Message{}).
+		Where("expires_at <= ? AND deleted_at IS NULL", now).
+		Limit(limit).
+		Pluck("id", &expired).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired messages: %w", err)
+	}
+
+	var retained []int
+	err = db.
+		Model(&models.Message{}).
+		Joins("JOIN "+retentionCutoffs+" rc ON rc.chat_id = messages.chat_id", now).
+		Where("messages.deleted_at IS NULL").
+		Where(retentionExpired("messages")).
+		Limit(limit).
+		Pluck("messages.id", &retained).Error
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to list messages beyond retention: %w", err)
+	}
+
+	var deleted int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = tombstoneMessages(tx, append(expired, retained...), now)
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// tombstoneMessages soft deletes the listed messages that are not deleted yet
+// and returns their number. Their earlier versions are deleted as well, so no
+// text of an expired message is kept.
+func tombstoneMessages(tx *gorm.DB, ids []int, now time.Time) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := tx.
+		Model(&models.Message{}).
+		Where("id IN ? AND deleted_at IS NULL", ids).
+		Updates(map[string]interface{}{
+			"text":       "",
+			"deleted_at": now,
+		})
+
+	if err := result.Error; err != nil {
+		return 0, fmt.Errorf("failed to delete expired messages: %w", err)
+	}
+
+	if err := tx.Where("message_id IN ?", ids).Delete(&models.PinnedMessage{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to unpin expired messages: %w", err)
+	}
+
+	if err := tx.Where("message_id IN ?", ids).Delete(&models.Attachment{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to delete attachments: %w", err)
+	}
+
+	if err := tx.Where("message_id IN ?", ids).Delete(&models.MessageRevision{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to delete revisions: %w", err)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// lockActiveMessage loads a message that has been neither deleted nor expired
+// and locks its row until the end of the transaction.
+func lockActiveMessage(tx *gorm.DB, chatID int, messageID int, message *models.Message) error {
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(unexpiredMessages(time.Now())).
+		Preload("Author").
+		Where("id = ? AND chat_id = ? AND deleted_at IS NULL", messageID, chatID).
+		Take(message).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("message with id %d not found", messageID)
+		}
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *messageRepository) ListRevisions(ctx context.Context, chatID int, messageID int) ([]models.MessageRevision, error) {
+	db := repo.db.WithContext(ctx)
+
+	var count int64
+	err := db.
+		Model(&models.Message{}).
+		Scopes(unexpiredMessages(time.Now())).
+		Where("id = ? AND chat_id = ?", messageID, chatID).
+		Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("check message existence: %w", err)
+	}
+
+	if count == 0 {
+		return nil, fmt.Errorf("message with id %d not found", messageID)
+	}
+
+	revisions := []models.MessageRevision{}
+	err = db.Where("message_id = ?", messageID).Order("id ASC").Find(&revisions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// checkParent makes sure a reply goes to a root message of the same chat that
+// has been neither deleted nor expired. Threads are a single level deep. The
+// parent stays locked against deletion until the end of the transaction.
+func checkParent(tx *gorm.DB, message *models.Message) error {
+	var parent models.Message
+	err := tx.
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Scopes(unexpiredMessages(time.Now())).
+		Select("id", "parent_id").
+		Where("id = ? AND chat_id = ? AND deleted_at IS NULL", *message.ParentID, message.ChatID).
+		Take(&parent).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("parent message with id %d is not in chat %d", *message.ParentID, message.ChatID)
+		}
+		return fmt.Errorf("failed to get parent message: %w", err)
+	}
+
+	if parent.ParentID != nil {
+		return fmt.Errorf("parent message with id %d is itself a reply", parent.ID)
+	}
+
+	return nil
+}
+
+type replyStatsRow struct {
+	ParentID    int
+	ReplyCount  int64
+	LastReplyAt time.Time
+}
+
+// attachReplyStats fills in the thread summary of root messages with a single
+// aggregate query over idx_messages_parent_id_created_at.
+func attachReplyStats(db *gorm.DB, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	var rows []replyStatsRow
+	err := db.
+		Model(&models.Message{}).
+		Select("parent_id, COUNT(*) AS reply_count, MAX(created_at) AS last_reply_at").
+		Where("parent_id IN ? AND deleted_at IS NULL", ids).
+		Group("parent_id").
+		Scan(&rows).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to count replies: %w", err)
+	}
+
+	stats := make(map[int]replyStatsRow, len(rows))
+	for _, row := range rows {
+		stats[row.ParentID] = row
+	}
+
+	for i := range messages {
+		if row, ok := stats[messages[i].ID]; ok {
+			messages[i].ReplyCount = row.ReplyCount
+			messages[i].LastReplyAt = &row.LastReplyAt
+		}
+	}
+
+	return nil
+}
+
+// saveRevision records the current version of message before it is replaced.
+func saveRevision(tx *gorm.DB, message *models.Message, replacedAt time.Time) error {
+	revision := models.MessageRevision{
+		MessageID:  message.ID,
+		Text:       message.Text,
+		CreatedAt:  message.CreatedAt,
+		ReplacedAt: replacedAt,
+	}
+
+	if message.EditedAt != nil {
+		revision.CreatedAt = *message.EditedAt
+	}
+
+	if err := tx.Create(&revision).Error; err != nil {
+		return fmt.Errorf("failed to save revision: %w", err)
+	}
+
+	return nil
+}
+
+// messagePageScope applies the keyset conditions of page. The ordering matches
+// idx_messages_chat_id_created_at so the index can serve both directions.
+func messagePageScope(page MessagePage) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch {
+		case page.Before != nil:
+			db = db.
+				Where("(created_at, id) < (?, ?)", page.Before.CreatedAt, page.Before.ID).
+				Order("created_at DESC, id DESC")
+		case page.After != nil:
+			db = db.
+				Where("(created_at, id) > (?, ?)", page.After.CreatedAt, page.After.ID).
+				Order("created_at ASC, id ASC")
+		default:
+			db = db.Order("created_at DESC, id DESC")
+		}
+
+		return db.Limit(page.Limit)
+	}
+}
+
+// rootMessages leaves out thread replies.
+func rootMessages(db *gorm.DB) *gorm.DB {
+	return db.Where("parent_id IS NULL")
+}
+
+// retentionCutoffs has a row per chat with a retention policy: the time up
+// to which its messages are too old and the position of its oldest message
+// still among the newest retention_max_messages, replies included. Each
+// cutoff is computed once per query rather than once per message. The only
+// placeholder is the current time.
+const retentionCutoffs = `(
+	SELECT c.id AS chat_id,
+		?::timestamptz - c.retention_max_age * INTERVAL '1 second' AS max_age_cutoff,
+		newest.created_at AS max_messages_cutoff_at, newest.id AS max_messages_cutoff_id
+	FROM chats c
+	LEFT JOIN LATERAL (
+		SELECT m.created_at, m.id FROM messages m
+		WHERE m.chat_id = c.id AND c.retention_max_messages IS NOT NULL
+		ORDER BY m.created_at DESC, m.id DESC
+		OFFSET c.retention_max_messages - 1 LIMIT 1
+	) newest ON TRUE
+	WHERE c.retention_max_age IS NOT NULL OR c.retention_max_messages IS NOT NULL
+)`
+
+// retentionExpired matches the messages aliased as table that are beyond
+// the cutoffs joined as rc. It is NULL rather than false when the chat has no
+// policy or fewer messages than the limit.
+func retentionExpired(table string) string {
+	return fmt.Sprintf(`(%[1]s.created_at <= rc.max_age_cutoff
+	OR (%[1]s.created_at, %[1]s.id) < (rc.max_messages_cutoff_at, rc.max_messages_cutoff_id))`, table)
+}
+
+// unexpiredCondition matches the messages aliased as table that have neither
+// passed their expires_at nor fallen out of the retention policy of their
+// chat. Both placeholders are the current time.
+func unexpiredCondition(table string) string {
+	return fmt.Sprintf(`(%[1]s.expires_at IS NULL OR %[1]s.expires_at > ?)
+	AND NOT EXISTS (SELECT 1 FROM %[2]s rc WHERE rc.chat_id = %[1]s.chat_id AND %[3]s)`,
+		table, retentionCutoffs, retentionExpired(table))
+}
+
+// unexpiredMessages leaves out expired messages, which are kept as tombstones
+// once the reaper gets to them.
+func unexpiredMessages(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(unexpiredCondition("messages"), now, now)
+	}
+}
+
+// orderNewestFirst reverses windows fetched in ascending order.
+func orderNewestFirst(messages []models.Message, page MessagePage) []models.Message {
+	if page.Before == nil && page.After != nil {
+		slices.Reverse(messages)
+	}
+	return messages
 }
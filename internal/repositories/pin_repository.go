@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PinRepository interface {
+	PinMessage(ctx context.Context, pin *models.PinnedMessage, limit int) error
+	UnpinMessage(ctx context.Context, chatID int, messageID int) error
+}
+
+type pinRepository struct {
+	db *gorm.DB
+}
+
+func NewPinRepository(db *gorm.DB) PinRepository {
+	return &pinRepository{db: db}
+}
+
+// PinMessage pins a message that has not been deleted unless the chat already
+// has limit pinned messages. Pinning a message twice keeps the original pin,
+// which is loaded into pin.
+func (repo *pinRepository) PinMessage(ctx context.Context, pin *models.PinnedMessage, limit int) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the chat serializes concurrent pins, so the limit cannot
+		// be exceeded between the count and the insert.
+		var chat models.Chat
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Take(&chat, pin.ChatID).Error
+
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("chat with id %d not found", pin.ChatID)
+			}
+			return fmt.Errorf("failed to lock chat: %w", err)
+		}
+
+		var message models.Message
+		err = tx.Preload("Author").
+			Where("id = ? AND chat_id = ? AND deleted_at IS NULL", pin.MessageID, pin.ChatID).
+			Take(&message).Error
+
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("message with id %d not found", pin.MessageID)
+			}
+			return fmt.Errorf("failed to get message: %w", err)
+		}
+
+		pin.Message = &message
+
+		var existing models.PinnedMessage
+		err = tx.Where("chat_id = ? AND message_id = ?", pin.ChatID, pin.MessageID).Take(&existing).Error
+		if err == nil {
+			pin.PinnedBy = existing.PinnedBy
+			pin.PinnedAt = existing.PinnedAt
+			return nil
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("check pin existence: %w", err)
+		}
+
+		var count int64
+		if err := tx.Model(&models.PinnedMessage{}).Where("chat_id = ?", pin.ChatID).Count(&count).Error; err != nil {
+			return fmt.Errorf("count pinned messages: %w", err)
+		}
+
+		if count >= int64(limit) {
+			return fmt.Errorf("pin limit of %d reached in chat %d", limit, pin.ChatID)
+		}
+
+		if err := tx.Omit(clause.Associations).Create(pin).Error; err != nil {
+			return fmt.Errorf("failed to pin message: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (repo *pinRepository) UnpinMessage(ctx context.Context, chatID int, messageID int) error {
+	result := repo.db.WithContext(ctx).
+		Where("chat_id = ? AND message_id = ?", chatID, messageID).
+		Delete(&models.PinnedMessage{})
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to unpin message: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("pinned message %d not found", messageID)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReactionRepository interface {
+	AddReaction(ctx context.Context, chatID int, reaction *models.MessageReaction) error
+	RemoveReaction(ctx context.Context, chatID int, reaction *models.MessageReaction) error
+	Summarize(ctx context.Context, messageIDs []int, userID string) (map[int][]models.ReactionSummary, error)
+}
+
+type reactionRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionRepository(db *gorm.DB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+// AddReaction reacts to a message that has not been deleted. Adding the same
+// reaction twice is not an error.
+func (repo *reactionRepository) AddReaction(ctx context.Context, chatID int, reaction *models.MessageReaction) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Message{}).
+			Where("id = ? AND chat_id = ? AND deleted_at IS NULL", reaction.MessageID, chatID).
+			Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check message existence: %w", err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("message with id %d not found", reaction.MessageID)
+		}
+
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction).Error
+		if err != nil {
+			return fmt.Errorf("failed to add reaction: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (repo *reactionRepository) RemoveReaction(ctx context.Context, chatID int, reaction *models.MessageReaction) error {
+	result := repo.db.WithContext(ctx).
+		Where("message_id = ? AND user_id = ? AND emoji = ?", reaction.MessageID, reaction.UserID, reaction.Emoji).
+		Where("message_id IN (SELECT id FROM messages WHERE chat_id = ?)", chatID).
+		Delete(&models.MessageReaction{})
+
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("reaction %s to message %d not found", reaction.Emoji, reaction.MessageID)
+	}
+
+	return nil
+}
+
+type reactionSummaryRow struct {
+	MessageID   int
+	Emoji       string
+	Count       int64
+	ReactedByMe bool
+}
+
+// Summarize aggregates the reactions to a batch of messages in a single
+// query. Emojis of a message are ordered by their first use.
+func (repo *reactionRepository) Summarize(ctx context.Context, messageIDs []int, userID string) (map[int][]models.ReactionSummary, error) {
+	summaries := make(map[int][]models.ReactionSummary)
+	if len(messageIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []reactionSummaryRow
+	err := repo.db.WithContext(ctx).
+		Model(&models.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted_by_me", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("message_id, MIN(created_at), emoji").
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize reactions: %w", err)
+	}
+
+	for _, row := range rows {
+		summaries[row.MessageID] = append(summaries[row.MessageID], models.ReactionSummary{
+			Emoji:       row.Emoji,
+			Count:       row.Count,
+			ReactedByMe: row.ReactedByMe,
+		})
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledMessageRepository interface {
+	Create(ctx context.Context, scheduled *models.ScheduledMessage) error
+	DeliverDue(ctx context.Context, limit int, allowed func(*models.ScheduledMessage) (bool, error)) ([]models.Message, error)
+}
+
+type scheduledMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageRepository(db *gorm.DB) ScheduledMessageRepository {
+	return &scheduledMessageRepository{db: db}
+}
+
+func (repo *scheduledMessageRepository) Create(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Chat{}).Where("id = ?", scheduled.ChatID).Count(&count).Error
+
+		if err != nil {
+			return fmt.Errorf("check chat existence: %w", err)
+		}
+
+		if count == 0 {
+			return fmt.Errorf("chat with id %d not found", scheduled.ChatID)
+		}
+
+		if scheduled.ParentID != nil {
+			if err := checkParent(tx, &models.Message{ChatID: scheduled.ChatID, ParentID: scheduled.ParentID}); err != nil {
+				return err
+			}
+		}
+
+		if scheduled.Author != nil {
+			if err := upsertUser(tx, scheduled.Author); err != nil {
+				return err
+			}
+			scheduled.AuthorID = scheduled.Author.ID
+		}
+
+		if err := tx.Omit(clause.Associations).Create(scheduled).Error; err != nil {
+			return fmt.Errorf("failed to create scheduled message: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeliverDue posts up to limit scheduled messages whose time has come and
+// returns the created messages. Rows are claimed with SKIP LOCKED, so several
+// instances may deliver concurrently without posting a message twice.
+// Messages that allowed rejects and replies whose root message is gone are
+// discarded; an error from allowed rolls the whole batch back.
+func (repo *scheduledMessageRepository) DeliverDue(ctx context.Context, limit int, allowed func(*models.ScheduledMessage) (bool, error)) ([]models.Message, error) {
+	messages := []models.Message{}
+
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		due := []models.ScheduledMessage{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("Author").
+			Where("send_at <= ?", time.Now()).
+			Order("send_at ASC, id ASC").
+			Limit(limit).
+			Find(&due).Error
+
+		if err != nil {
+			return fmt.Errorf("failed to claim scheduled messages: %w", err)
+		}
+
+		for i := range due {
+			scheduled := &due[i]
+
+			ok, err := allowed(scheduled)
+			if err != nil {
+				return err
+			}
+
+			message := models.Message{
+				ChatID:    scheduled.ChatID,
+				ParentID:  scheduled.ParentID,
+				AuthorID:  &scheduled.AuthorID,
+				Text:      scheduled.Text,
+				ExpiresAt: scheduled.ExpiresAt,
+				Author:    scheduled.Author,
+			}
+
+			// The root may have been deleted or expired since the reply was
+			// scheduled.
+			if ok && message.ParentID != nil {
+				if err := checkParent(tx, &message); err != nil {
+					if !strings.Contains(err.Error(), "parent message") {
+						return err
+					}
+					ok = false
+				}
+			}
+
+			if ok {
+				if err := tx.Omit(clause.Associations).Create(&message).Error; err != nil {
+					return fmt.Errorf("failed to post scheduled message %d: %w", scheduled.ID, err)
+				}
+				messages = append(messages, message)
+			}
+
+			if err := tx.Delete(scheduled).Error; err != nil {
+				return fmt.Errorf("failed to delete scheduled message %d: %w", scheduled.ID, err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
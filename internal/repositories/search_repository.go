@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// searchConfig must match the configuration of the messages.text_tsv column,
+// otherwise the GIN index cannot be used.
+const searchConfig = "simple"
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5"
+
+// escapedText is the message text with HTML special characters escaped. The
+// headline is built from it rather than from the raw text, so that the only
+// markup in a snippet is the <mark> tags.
+const escapedText = `replace(replace(replace(replace(replace(m.text,
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+
+// SearchParams describes a full-text query over the chats UserID is a member
+// of. ChatID restricts the search to a single chat when it is non-zero.
+type SearchParams struct {
+	UserID string
+	Query  string
+	ChatID int
+	Limit  int
+	Offset int
+}
+
+type SearchRepository interface {
+	SearchMessages(ctx context.Context, params SearchParams) ([]models.SearchHit, int64, error)
+}
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+func (repo *searchRepository) SearchMessages(ctx context.Context, params SearchParams) ([]models.SearchHit, int64, error) {
+	now := time.Now()
+	tx := repo.db.WithContext(ctx).
+		Table("messages AS m").
+		Joins("JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = ?", params.UserID).
+		Joins("CROSS JOIN websearch_to_tsquery(?, ?) AS q", searchConfig, params.Query).
+		Where("m.text_tsv @@ q AND m.deleted_at IS NULL").
+		Where(unexpiredCondition("m"), now, now)
+
+	if params.ChatID != 0 {
+		tx = tx.Where("m.chat_id = ?", params.ChatID)
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search hits: %w", err)
+	}
+
+	hits := []models.SearchHit{}
+	err := tx.
+		Select(`m.id AS message_id, m.chat_id, m.created_at,
+			ts_rank(m.text_tsv, q) AS rank,
+			ts_headline(?, `+escapedText+`, q, ?) AS snippet`, searchConfig, headlineOptions).
+		Order("rank DESC, m.id DESC").
+		Limit(params.Limit).
+		Offset(params.Offset).
+		Scan(&hits).Error
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	return hits, total, nil
+}
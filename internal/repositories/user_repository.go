@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertUser makes sure the user row exists. A non-empty name replaces the
+// stored one so that display names follow the identity provider.
+func upsertUser(tx *gorm.DB, user *models.User) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"name": gorm.Expr("EXCLUDED.name")}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("EXCLUDED.name <> '' AND EXCLUDED.name <> users.name"),
+		}},
+	}).Create(user).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+
+	if err := tx.Take(user, "id = ?", user.ID).Error; err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	return nil
+}
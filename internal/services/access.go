@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// requireMember returns the caller's membership in a chat. It fails with
+// ErrChatNotFound when the chat does not exist and with ErrForbidden when the
+// caller is not a member of it.
+func requireMember(ctx context.Context, members repo.MemberRepository, chatID int) (*models.ChatMember, error) {
+	caller, err := callerUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := members.GetMember(ctx, chatID, caller.ID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not a member"):
+			return nil, ErrForbidden
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("check membership: %w", err)
+	}
+
+	member.User = caller
+	return member, nil
+}
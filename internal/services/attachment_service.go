@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+	"github.com/jonx8/chat-service/internal/storage"
+	"github.com/jonx8/chat-service/internal/thumbnails"
+)
+
+var (
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrAttachmentTypeInvalid = errors.New("attachment type is not allowed")
+)
+
+// AttachmentLimits restricts uploads. AllowedTypes lists media types without
+// parameters, e.g. image/png.
+type AttachmentLimits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+type AttachmentService interface {
+	UploadAttachment(ctx context.Context, chatID int, upload *dto.AttachmentUpload) (*models.Attachment, error)
+	OpenAttachment(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error)
+	OpenThumbnail(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error)
+}
+
+type attachmentService struct {
+	attachmentRepository repo.AttachmentRepository
+	memberRepository     repo.MemberRepository
+	blobStore            storage.BlobStore
+	limits               AttachmentLimits
+}
+
+func NewAttachmentService(
+	attachmentRepository repo.AttachmentRepository,
+	memberRepository repo.MemberRepository,
+	blobStore storage.BlobStore,
+	limits AttachmentLimits,
+) AttachmentService {
+	return &attachmentService{
+		attachmentRepository: attachmentRepository,
+		memberRepository:     memberRepository,
+		blobStore:            blobStore,
+		limits:               limits,
+	}
+}
+
+// UploadAttachment stores a file that the caller may then attach to a message
+// of the chat. Thumbnails of images are generated later by thumbnails.Worker.
+func (service *attachmentService) UploadAttachment(ctx context.Context, chatID int, upload *dto.AttachmentUpload) (*models.Attachment, error) {
+	member, err := requirePermission(ctx, service.memberRepository, chatID, permPostMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.Size > service.limits.MaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	if !slices.Contains(service.limits.AllowedTypes, upload.ContentType) {
+		return nil, ErrAttachmentTypeInvalid
+	}
+
+	key, err := newStorageKey(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.blobStore.Put(ctx, key, upload.Content, upload.Size, upload.ContentType); err != nil {
+		return nil, fmt.Errorf("store attachment: %w", err)
+	}
+
+	attachment := &models.Attachment{
+		ChatID:      chatID,
+		UploaderID:  &member.UserID,
+		FileName:    upload.FileName,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		StorageKey:  key,
+	}
+	if thumbnails.Supported(upload.ContentType) {
+		pending := models.ThumbnailPending
+		attachment.ThumbnailStatus = &pending
+	}
+
+	if err := service.attachmentRepository.Create(ctx, attachment); err != nil {
+		if err := service.blobStore.Delete(ctx, key); err != nil {
+			slog.Error("Failed to delete orphaned blob", "error", err, "key", key)
+		}
+
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("create attachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// OpenAttachment returns an attachment of the chat together with its content.
+// Attachments not linked to a message yet are only returned to their uploader.
+// The caller must close the content.
+func (service *attachmentService) OpenAttachment(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := service.getAttachment(ctx, chatID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return service.open(ctx, attachment, attachment.StorageKey)
+}
+
+// OpenThumbnail is like OpenAttachment but returns the thumbnail, which is
+// always a thumbnails.ContentType image. Attachments without a thumbnail are
+// reported as missing.
+func (service *attachmentService) OpenThumbnail(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := service.getAttachment(ctx, chatID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if attachment.ThumbnailKey == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	return service.open(ctx, attachment, *attachment.ThumbnailKey)
+}
+
+func (service *attachmentService) getAttachment(ctx context.Context, chatID int, attachmentID int) (*models.Attachment, error) {
+	member, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := service.attachmentRepository.GetByID(ctx, chatID, attachmentID, member.UserID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("get attachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+func (service *attachmentService) open(ctx context.Context, attachment *models.Attachment, key string) (*models.Attachment, io.ReadSeekCloser, error) {
+	content, err := service.blobStore.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, fmt.Errorf("open attachment: %w", err)
+	}
+
+	return attachment, content, nil
+}
+
+// newStorageKey returns an unguessable key. File names are not part of keys
+// because they come from clients.
+func newStorageKey(chatID int) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate storage key: %w", err)
+	}
+
+	return fmt.Sprintf("chats/%d/%s", chatID, hex.EncodeToString(buf)), nil
+}
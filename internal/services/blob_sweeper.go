@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	repo "github.com/jonx8/chat-service/internal/repositories"
+	"github.com/jonx8/chat-service/internal/storage"
+)
+
+const (
+	// blobSweepInterval is how often blobs of deleted attachments are removed.
+	blobSweepInterval = time.Minute
+
+	// blobSweepBatchSize is the number of attachments or blobs handled at once.
+	blobSweepBatchSize = 100
+
+	// unlinkedUploadTTL is how long an upload may wait to be attached to a
+	// message before it is deleted.
+	unlinkedUploadTTL = 24 * time.Hour
+)
+
+// BlobSweeper removes blobs that no attachment refers to anymore: those of
+// attachments deleted with their message or chat, and of uploads never
+// attached to a message.
+type BlobSweeper struct {
+	attachmentRepository repo.AttachmentRepository
+	blobStore            storage.BlobStore
+}
+
+func NewBlobSweeper(attachmentRepository repo.AttachmentRepository, blobStore storage.BlobStore) *BlobSweeper {
+	return &BlobSweeper{
+		attachmentRepository: attachmentRepository,
+		blobStore:            blobStore,
+	}
+}
+
+// Run removes orphaned blobs until ctx is cancelled.
+func (s *BlobSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(blobSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deleteUnlinked(ctx)
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BlobSweeper) deleteUnlinked(ctx context.Context) {
+	for ctx.Err() == nil {
+		deleted, err := s.attachmentRepository.DeleteUnlinked(ctx, time.Now().Add(-unlinkedUploadTTL), blobSweepBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to delete unlinked attachments", "error", err)
+			}
+			return
+		}
+
+		if deleted < blobSweepBatchSize {
+			return
+		}
+	}
+}
+
+// sweep deletes queued blobs batch by batch. Blobs that could not be deleted
+// stay queued and are retried on the next tick.
+func (s *BlobSweeper) sweep(ctx context.Context) {
+	for ctx.Err() == nil {
+		keys, err := s.attachmentRepository.ListOrphanedBlobs(ctx, blobSweepBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to list orphaned blobs", "error", err)
+			}
+			return
+		}
+
+		deleted := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if err := s.blobStore.Delete(ctx, key); err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to delete orphaned blob", "error", err, "key", key)
+				}
+				continue
+			}
+			deleted = append(deleted, key)
+		}
+
+		if err := s.attachmentRepository.ForgetOrphanedBlobs(ctx, deleted); err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to forget orphaned blobs", "error", err)
+			}
+			return
+		}
+
+		if len(keys) < blobSweepBatchSize || len(deleted) == 0 {
+			return
+		}
+	}
+}
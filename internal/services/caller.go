@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jonx8/chat-service/internal/auth"
+	"github.com/jonx8/chat-service/internal/models"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// callerUser returns the authenticated caller as a user record.
+func callerUser(ctx context.Context) (*models.User, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.Subject == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	return &models.User{ID: identity.Subject, Name: identity.Name}, nil
+}
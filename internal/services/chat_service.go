@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/events"
 	"github.com/jonx8/chat-service/internal/models"
 	repo "github.com/jonx8/chat-service/internal/repositories"
 )
@@ -14,25 +15,51 @@ import (
 var (
 	ErrChatNotFound      = errors.New("chat not found")
 	ErrChatAlreadyExists = errors.New("chat already exists")
+	ErrDirectChatToSelf  = errors.New("direct chat with oneself")
 )
 
 type ChatService interface {
 	CreateChat(ctx context.Context, request *dto.CreateChatRequest) (*models.Chat, error)
-	GetChat(ctx context.Context, id int, limit int) (*models.Chat, error)
+	OpenDirectChat(ctx context.Context, userID string) (*models.Chat, bool, error)
+	GetChat(ctx context.Context, id int, req *dto.PageRequest) (*dto.ChatResponse, error)
 	DeleteChat(ctx context.Context, id int) error
+	ListChats(ctx context.Context, req *dto.ListChatsRequest) (*dto.ChatList, error)
+	CheckAccess(ctx context.Context, id int) error
+	MarkRead(ctx context.Context, id int, req *dto.MarkReadRequest) (*models.ChatMember, error)
+	UpdateRetention(ctx context.Context, id int, req *dto.UpdateRetentionRequest) (*models.Retention, error)
 }
 
 type chatService struct {
-	chatRepository repo.ChatRepository
+	chatRepository     repo.ChatRepository
+	memberRepository   repo.MemberRepository
+	reactionRepository repo.ReactionRepository
+	publisher          events.Publisher
 }
 
-func NewChatService(chatRepository repo.ChatRepository) ChatService {
-	return &chatService{chatRepository: chatRepository}
+func NewChatService(
+	chatRepository repo.ChatRepository,
+	memberRepository repo.MemberRepository,
+	reactionRepository repo.ReactionRepository,
+	publisher events.Publisher,
+) ChatService {
+	return &chatService{
+		chatRepository:     chatRepository,
+		memberRepository:   memberRepository,
+		reactionRepository: reactionRepository,
+		publisher:          publisher,
+	}
 }
 
 func (service *chatService) CreateChat(ctx context.Context, req *dto.CreateChatRequest) (*models.Chat, error) {
+	owner, err := callerUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	chat := &models.Chat{
+		Kind:  models.ChatKindGroup,
 		Title: req.Title,
+		Owner: owner,
 	}
 	if err := service.chatRepository.CreateIfNotExists(ctx, chat); err != nil {
 		if strings.Contains(err.Error(), "already exists") {
@@ -44,23 +71,144 @@ func (service *chatService) CreateChat(ctx context.Context, req *dto.CreateChatR
 	return chat, nil
 }
 
-func (service *chatService) GetChat(ctx context.Context, id int, limit int) (*models.Chat, error) {
-	chat, err := service.chatRepository.GetByID(ctx, id, limit)
+// OpenDirectChat returns the direct chat between the caller and another user,
+// creating it if needed. The boolean reports whether the chat is new.
+func (service *chatService) OpenDirectChat(ctx context.Context, userID string) (*models.Chat, bool, error) {
+	caller, err := callerUser(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if caller.ID == userID {
+		return nil, false, ErrDirectChatToSelf
+	}
+
+	chat, created, err := service.chatRepository.GetOrCreateDirect(ctx, caller, &models.User{ID: userID})
+	if err != nil {
+		return nil, false, fmt.Errorf("open direct chat: %w", err)
+	}
+
+	return chat, created, nil
+}
+
+func (service *chatService) GetChat(ctx context.Context, id int, req *dto.PageRequest) (*dto.ChatResponse, error) {
+	member, err := requireMember(ctx, service.memberRepository, id)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := newMessagePage(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := service.chatRepository.GetByID(ctx, id, page)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return nil, ErrChatNotFound
 		}
 		return nil, fmt.Errorf("get chat: %w", err)
 	}
-	return chat, nil
+
+	unread, err := service.memberRepository.CountUnread(ctx, id, member.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("get chat: %w", err)
+	}
+
+	var cursors dto.PageCursors
+	chat.Messages, cursors = trimPage(chat.Messages, req, page)
+
+	err = attachReactions(ctx, service.reactionRepository, member.UserID, messagePointers(chat.Messages))
+	if err != nil {
+		return nil, fmt.Errorf("get chat: %w", err)
+	}
+
+	return &dto.ChatResponse{Chat: chat, Cursors: cursors, UnreadCount: unread}, nil
 }
 
 func (service *chatService) DeleteChat(ctx context.Context, id int) error {
+	if _, err := requirePermission(ctx, service.memberRepository, id, permDeleteChat); err != nil {
+		return err
+	}
+
 	if err := service.chatRepository.DeleteByID(ctx, id); err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return ErrChatNotFound
 		}
 		return fmt.Errorf("delete chat: %w", err)
 	}
+
+	service.publisher.Publish(events.Event{
+		Type:   events.TypeChatDeleted,
+		ChatID: id,
+		Data:   map[string]int{"chat_id": id},
+	})
+
 	return nil
 }
+
+func (service *chatService) ListChats(ctx context.Context, req *dto.ListChatsRequest) (*dto.ChatList, error) {
+	caller, err := callerUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chats, total, err := service.chatRepository.List(ctx, repo.ChatListParams{
+		MemberID:        caller.ID,
+		Limit:           req.Limit,
+		Offset:          req.Offset,
+		TitlePrefix:     req.TitlePrefix,
+		OrderByActivity: req.Sort == dto.ChatSortLastActivity,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list chats: %w", err)
+	}
+
+	return &dto.ChatList{Chats: chats, Total: total}, nil
+}
+
+// CheckAccess reports whether the caller may follow the chat's activity.
+func (service *chatService) CheckAccess(ctx context.Context, id int) error {
+	_, err := requireMember(ctx, service.memberRepository, id)
+	return err
+}
+
+// MarkRead advances the caller's read position in a chat. Positions older than
+// the current one are ignored.
+func (service *chatService) MarkRead(ctx context.Context, id int, req *dto.MarkReadRequest) (*models.ChatMember, error) {
+	member, err := requireMember(ctx, service.memberRepository, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := service.memberRepository.MarkRead(ctx, id, member.UserID, req.MessageID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not a member"):
+			return nil, ErrForbidden
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("mark read: %w", err)
+	}
+
+	return updated, nil
+}
+
+// UpdateRetention replaces the retention policy of a chat. Messages beyond the
+// new policy disappear at once and are deleted by the RetentionReaper.
+func (service *chatService) UpdateRetention(ctx context.Context, id int, req *dto.UpdateRetentionRequest) (*models.Retention, error) {
+	if _, err := requirePermission(ctx, service.memberRepository, id, permManageRetention); err != nil {
+		return nil, err
+	}
+
+	retention := models.Retention{MaxAge: req.MaxAge, MaxMessages: req.MaxMessages}
+	if err := service.chatRepository.UpdateRetention(ctx, id, retention); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("update retention: %w", err)
+	}
+
+	return &retention, nil
+}
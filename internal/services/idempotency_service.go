@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+const (
+	// idempotencyStaleAfter is how long a request may run before its key is
+	// considered abandoned, for instance after a crash, and may be reused.
+	idempotencyStaleAfter = time.Minute
+
+	// idempotencyPruneInterval is how often expired keys are deleted.
+	idempotencyPruneInterval = time.Minute
+
+	// idempotencyPruneBatchSize bounds the number of keys deleted at once.
+	idempotencyPruneBatchSize = 500
+)
+
+var (
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+	ErrIdempotencyKeyInUse  = errors.New("request with the idempotency key is in progress")
+)
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, key string, fingerprint string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, key *models.IdempotencyKey) error
+	Release(ctx context.Context, key *models.IdempotencyKey) error
+}
+
+type idempotencyService struct {
+	idempotencyRepository repo.IdempotencyRepository
+	window                time.Duration
+}
+
+// NewIdempotencyService returns a service that remembers the responses of
+// requests for window.
+func NewIdempotencyService(idempotencyRepository repo.IdempotencyRepository, window time.Duration) IdempotencyService {
+	return &idempotencyService{
+		idempotencyRepository: idempotencyRepository,
+		window:                window,
+	}
+}
+
+// Begin reserves a key of the caller for a request with the given
+// fingerprint. If the key already holds the response of the same request, it
+// is returned with StatusCode set and the request must not be executed again.
+func (service *idempotencyService) Begin(ctx context.Context, key string, fingerprint string) (*models.IdempotencyKey, error) {
+	caller, err := callerUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &models.IdempotencyKey{UserID: caller.ID, Key: key, Fingerprint: fingerprint}
+
+	existing, err := service.idempotencyRepository.Reserve(ctx, record, now.Add(-service.window), now.Add(-idempotencyStaleAfter))
+	if err != nil {
+		if strings.Contains(err.Error(), "concurrently") {
+			return nil, ErrIdempotencyKeyInUse
+		}
+		return nil, fmt.Errorf("begin idempotent request: %w", err)
+	}
+
+	switch {
+	case existing == nil:
+		return record, nil
+	case existing.Fingerprint != fingerprint:
+		return nil, ErrIdempotencyKeyReused
+	case existing.StatusCode == nil:
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response of a request begun with Begin.
+func (service *idempotencyService) Complete(ctx context.Context, key *models.IdempotencyKey) error {
+	if err := service.idempotencyRepository.Complete(ctx, key); err != nil {
+		return fmt.Errorf("complete idempotent request: %w", err)
+	}
+	return nil
+}
+
+// Release forgets a request begun with Begin that has not completed, so that
+// it can be retried with the same key.
+func (service *idempotencyService) Release(ctx context.Context, key *models.IdempotencyKey) error {
+	if err := service.idempotencyRepository.Release(ctx, key); err != nil {
+		return fmt.Errorf("release idempotent request: %w", err)
+	}
+	return nil
+}
+
+// IdempotencyPruner deletes idempotency keys older than the window.
+type IdempotencyPruner struct {
+	idempotencyRepository repo.IdempotencyRepository
+	window                time.Duration
+}
+
+func NewIdempotencyPruner(idempotencyRepository repo.IdempotencyRepository, window time.Duration) *IdempotencyPruner {
+	return &IdempotencyPruner{
+		idempotencyRepository: idempotencyRepository,
+		window:                window,
+	}
+}
+
+// Run deletes expired keys until ctx is cancelled.
+func (p *IdempotencyPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		p.prune(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *IdempotencyPruner) prune(ctx context.Context) {
+	for ctx.Err() == nil {
+		deleted, err := p.idempotencyRepository.DeleteExpired(ctx, time.Now().Add(-p.window), idempotencyPruneBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to delete expired idempotency keys", "error", err)
+			}
+			return
+		}
+
+		if deleted < idempotencyPruneBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+var (
+	ErrMemberNotFound      = errors.New("member not found")
+	ErrMemberAlreadyExists = errors.New("member already exists")
+)
+
+type MemberService interface {
+	ListMembers(ctx context.Context, chatID int) ([]models.ChatMember, error)
+	AddMember(ctx context.Context, chatID int, req *dto.AddMemberRequest) (*models.ChatMember, error)
+	UpdateMember(ctx context.Context, chatID int, userID string, req *dto.UpdateMemberRequest) (*models.ChatMember, error)
+	RemoveMember(ctx context.Context, chatID int, userID string) error
+}
+
+type memberService struct {
+	memberRepository repo.MemberRepository
+	publisher        events.Publisher
+}
+
+func NewMemberService(memberRepository repo.MemberRepository, publisher events.Publisher) MemberService {
+	return &memberService{
+		memberRepository: memberRepository,
+		publisher:        publisher,
+	}
+}
+
+func (service *memberService) ListMembers(ctx context.Context, chatID int) ([]models.ChatMember, error) {
+	if _, err := requireMember(ctx, service.memberRepository, chatID); err != nil {
+		return nil, err
+	}
+
+	members, err := service.memberRepository.ListMembers(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+
+	return members, nil
+}
+
+func (service *memberService) AddMember(ctx context.Context, chatID int, req *dto.AddMemberRequest) (*models.ChatMember, error) {
+	caller, err := requirePermission(ctx, service.memberRepository, chatID, permManageMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+
+	if !canGrant(caller, role) {
+		return nil, ErrForbidden
+	}
+
+	member := &models.ChatMember{ChatID: chatID, UserID: req.UserID, Role: role}
+	if err := service.memberRepository.AddMember(ctx, member); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "already a member"):
+			return nil, ErrMemberAlreadyExists
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("add member: %w", err)
+	}
+
+	return member, nil
+}
+
+// UpdateMember changes the role of a member. Granting the owner role hands the
+// chat over to that member and leaves the previous owner as an admin.
+func (service *memberService) UpdateMember(ctx context.Context, chatID int, userID string, req *dto.UpdateMemberRequest) (*models.ChatMember, error) {
+	caller, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Role == models.RoleOwner {
+		return service.transferOwnership(ctx, caller, userID)
+	}
+
+	target, err := service.getMember(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManage(caller, target) || !canGrant(caller, req.Role) {
+		return nil, ErrForbidden
+	}
+
+	member, err := service.memberRepository.UpdateRole(ctx, chatID, userID, req.Role)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("update member: %w", err)
+	}
+
+	return member, nil
+}
+
+// RemoveMember lets members leave a chat and moderators remove members ranked
+// below them. The owner has to transfer the chat before leaving it. Live
+// subscriptions of the removed user end with the member.removed event.
+func (service *memberService) RemoveMember(ctx context.Context, chatID int, userID string) error {
+	caller, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return err
+	}
+
+	target, err := service.getMember(ctx, chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	leaving := target.UserID == caller.UserID
+	if target.Role == models.RoleOwner || (!leaving && !canManage(caller, target)) {
+		return ErrForbidden
+	}
+
+	if err := service.memberRepository.RemoveMember(ctx, chatID, userID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrMemberNotFound
+		}
+		return fmt.Errorf("remove member: %w", err)
+	}
+
+	service.publisher.Publish(events.Event{
+		Type:   events.TypeMemberRemoved,
+		ChatID: chatID,
+		Data:   events.MemberRemoved{ChatID: chatID, UserID: userID},
+	})
+
+	return nil
+}
+
+func (service *memberService) transferOwnership(ctx context.Context, caller *models.ChatMember, userID string) (*models.ChatMember, error) {
+	if !hasPermission(caller, permTransferOwnership) || caller.UserID == userID {
+		return nil, ErrForbidden
+	}
+
+	owner, err := service.memberRepository.TransferOwnership(ctx, caller.ChatID, caller.UserID, userID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not the owner"):
+			return nil, ErrForbidden
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("transfer ownership: %w", err)
+	}
+
+	return owner, nil
+}
+
+func (service *memberService) getMember(ctx context.Context, chatID int, userID string) (*models.ChatMember, error) {
+	member, err := service.memberRepository.GetMember(ctx, chatID, userID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not a member"):
+			return nil, ErrMemberNotFound
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("get member: %w", err)
+	}
+
+	return member, nil
+}
@@ -2,36 +2,340 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/events"
 	"github.com/jonx8/chat-service/internal/models"
 	repo "github.com/jonx8/chat-service/internal/repositories"
 )
 
+var (
+	ErrMessageNotFound   = errors.New("message not found")
+	ErrInvalidParent     = errors.New("invalid parent message")
+	ErrReactionNotFound  = errors.New("reaction not found")
+	ErrInvalidAttachment = errors.New("invalid attachment")
+)
+
 type MessageService interface {
-	CreateMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.Message, error)
+	CreateMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.Message, bool, error)
+	ScheduleMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.ScheduledMessage, error)
+	ListMessages(ctx context.Context, chatID int, req *dto.PageRequest) (*dto.MessagePage, error)
+	UpdateMessage(ctx context.Context, chatID int, messageID int, req *dto.UpdateMessageRequest) (*models.Message, error)
+	DeleteMessage(ctx context.Context, chatID int, messageID int) error
+	ListRevisions(ctx context.Context, chatID int, messageID int) ([]models.MessageRevision, error)
+	ListMessagesSince(ctx context.Context, chatID int, afterID int, limit int) ([]models.Message, error)
+	ListThread(ctx context.Context, chatID int, messageID int, req *dto.PageRequest) (*dto.ThreadPage, error)
+	AddReaction(ctx context.Context, chatID int, messageID int, emoji string) error
+	RemoveReaction(ctx context.Context, chatID int, messageID int, emoji string) error
 }
 
 type messageService struct {
-	messageRepository repo.MessageRepository
+	messageRepository   repo.MessageRepository
+	scheduledRepository repo.ScheduledMessageRepository
+	memberRepository    repo.MemberRepository
+	reactionRepository  repo.ReactionRepository
+	publisher           events.Publisher
 }
 
-func NewMessageService(messageRepository repo.MessageRepository) MessageService {
-	return &messageService{messageRepository: messageRepository}
+func NewMessageService(
+	messageRepository repo.MessageRepository,
+	scheduledRepository repo.ScheduledMessageRepository,
+	memberRepository repo.MemberRepository,
+	reactionRepository repo.ReactionRepository,
+	publisher events.Publisher,
+) MessageService {
+	return &messageService{
+		messageRepository:   messageRepository,
+		scheduledRepository: scheduledRepository,
+		memberRepository:    memberRepository,
+		reactionRepository:  reactionRepository,
+		publisher:           publisher,
+	}
 }
 
-func (service *messageService) CreateMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.Message, error) {
+// CreateMessage posts a message and reports whether it was created. A retry
+// with the ClientID of a message already sent returns that message instead.
+func (service *messageService) CreateMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.Message, bool, error) {
+	member, err := requirePermission(ctx, service.memberRepository, chatID, permPostMessage)
+	if err != nil {
+		return nil, false, err
+	}
+
 	message := &models.Message{
-		ChatID: chatID,
-		Text:   req.Text,
+		ChatID:    chatID,
+		ParentID:  req.ParentID,
+		Text:      req.Text,
+		ExpiresAt: req.ExpiresAt,
+		ClientID:  req.ClientID,
+		Author:    member.User,
+	}
+	for _, id := range req.AttachmentIDs {
+		message.Attachments = append(message.Attachments, models.Attachment{ID: id})
+	}
+
+	created, err := service.messageRepository.CreateMessage(ctx, message)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "parent message"):
+			return nil, false, ErrInvalidParent
+		case strings.Contains(err.Error(), "not available"):
+			return nil, false, ErrInvalidAttachment
+		case strings.Contains(err.Error(), "not found"):
+			return nil, false, ErrChatNotFound
+		}
+		return nil, false, fmt.Errorf("create message: %w", err)
+	}
+
+	// A retried message has already been delivered to subscribers.
+	if created {
+		service.publisher.Publish(events.Event{
+			ID:     message.ID,
+			Type:   events.TypeMessageCreated,
+			ChatID: chatID,
+			Data:   message,
+		})
+	}
+
+	return message, created, nil
+}
+
+// ScheduleMessage stores a message to be posted at req.SendAt by the
+// ScheduledMessageDispatcher. Scheduled messages have no attachments.
+func (service *messageService) ScheduleMessage(ctx context.Context, chatID int, req *dto.CreateMessageRequest) (*models.ScheduledMessage, error) {
+	member, err := requirePermission(ctx, service.memberRepository, chatID, permPostMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := &models.ScheduledMessage{
+		ChatID:    chatID,
+		ParentID:  req.ParentID,
+		Text:      req.Text,
+		SendAt:    *req.SendAt,
+		ExpiresAt: req.ExpiresAt,
+		Author:    member.User,
+	}
+
+	if err := service.scheduledRepository.Create(ctx, scheduled); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "parent message"):
+			return nil, ErrInvalidParent
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("schedule message: %w", err)
+	}
+
+	return scheduled, nil
+}
+
+func (service *messageService) ListMessages(ctx context.Context, chatID int, req *dto.PageRequest) (*dto.MessagePage, error) {
+	member, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := newMessagePage(req)
+	if err != nil {
+		return nil, err
 	}
-	if err := service.messageRepository.CreateMessage(ctx, message); err != nil {
+
+	messages, err := service.messageRepository.ListByChat(ctx, chatID, page)
+	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return nil, ErrChatNotFound
 		}
-		return nil, fmt.Errorf("create message: %w", err)
+		return nil, fmt.Errorf("list messages: %w", err)
+	}
+
+	result := &dto.MessagePage{}
+	result.Messages, result.Cursors = trimPage(messages, req, page)
+
+	err = attachReactions(ctx, service.reactionRepository, member.UserID, messagePointers(result.Messages))
+	if err != nil {
+		return nil, fmt.Errorf("list messages: %w", err)
+	}
+
+	return result, nil
+}
+
+func (service *messageService) UpdateMessage(ctx context.Context, chatID int, messageID int, req *dto.UpdateMessageRequest) (*models.Message, error) {
+	member, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := service.getChatMessage(ctx, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canEditMessage(member, existing) {
+		return nil, ErrForbidden
+	}
+
+	message, err := service.messageRepository.UpdateText(ctx, chatID, messageID, req.Text)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("update message: %w", err)
+	}
+
+	service.publisher.Publish(events.Event{
+		Type:   events.TypeMessageUpdated,
+		ChatID: chatID,
+		Data:   message,
+	})
+
+	return message, nil
+}
+
+func (service *messageService) DeleteMessage(ctx context.Context, chatID int, messageID int) error {
+	member, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := service.getChatMessage(ctx, chatID, messageID)
+	if err != nil {
+		return err
 	}
+
+	if !canDeleteMessage(member, existing) {
+		return ErrForbidden
+	}
+
+	message, err := service.messageRepository.SoftDelete(ctx, chatID, messageID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrMessageNotFound
+		}
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	// Deleted messages stay in the history as tombstones, so subscribers see
+	// the deletion as an update of the message.
+	service.publisher.Publish(events.Event{
+		Type:   events.TypeMessageUpdated,
+		ChatID: chatID,
+		Data:   message,
+	})
+
+	return nil
+}
+
+func (service *messageService) ListRevisions(ctx context.Context, chatID int, messageID int) ([]models.MessageRevision, error) {
+	if _, err := requireMember(ctx, service.memberRepository, chatID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := service.messageRepository.ListRevisions(ctx, chatID, messageID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("list revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+func (service *messageService) ListMessagesSince(ctx context.Context, chatID int, afterID int, limit int) ([]models.Message, error) {
+	if _, err := requireMember(ctx, service.memberRepository, chatID); err != nil {
+		return nil, err
+	}
+
+	messages, err := service.messageRepository.ListSince(ctx, chatID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list messages since %d: %w", afterID, err)
+	}
+	return messages, nil
+}
+
+// ListThread returns a root message and a page of its replies.
+func (service *messageService) ListThread(ctx context.Context, chatID int, messageID int, req *dto.PageRequest) (*dto.ThreadPage, error) {
+	member, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := newMessagePage(req)
+	if err != nil {
+		return nil, err
+	}
+
+	root, replies, err := service.messageRepository.ListThread(ctx, chatID, messageID, page)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("list thread: %w", err)
+	}
+
+	result := &dto.ThreadPage{Root: root}
+	result.Messages, result.Cursors = trimPage(replies, req, page)
+
+	messages := append(messagePointers(result.Messages), root)
+	if err := attachReactions(ctx, service.reactionRepository, member.UserID, messages); err != nil {
+		return nil, fmt.Errorf("list thread: %w", err)
+	}
+
+	return result, nil
+}
+
+// AddReaction reacts to a message on behalf of the caller. Reacting twice
+// with the same emoji has no further effect.
+func (service *messageService) AddReaction(ctx context.Context, chatID int, messageID int, emoji string) error {
+	member, err := requirePermission(ctx, service.memberRepository, chatID, permPostMessage)
+	if err != nil {
+		return err
+	}
+
+	reaction := &models.MessageReaction{MessageID: messageID, UserID: member.UserID, Emoji: emoji}
+	if err := service.reactionRepository.AddReaction(ctx, chatID, reaction); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrMessageNotFound
+		}
+		return fmt.Errorf("add reaction: %w", err)
+	}
+
+	return nil
+}
+
+func (service *messageService) RemoveReaction(ctx context.Context, chatID int, messageID int, emoji string) error {
+	member, err := requireMember(ctx, service.memberRepository, chatID)
+	if err != nil {
+		return err
+	}
+
+	reaction := &models.MessageReaction{MessageID: messageID, UserID: member.UserID, Emoji: emoji}
+	if err := service.reactionRepository.RemoveReaction(ctx, chatID, reaction); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrReactionNotFound
+		}
+		return fmt.Errorf("remove reaction: %w", err)
+	}
+
+	return nil
+}
+
+// getChatMessage loads a message for a permission check. Messages of other
+// chats are reported as missing.
+func (service *messageService) getChatMessage(ctx context.Context, chatID int, messageID int) (*models.Message, error) {
+	message, err := service.messageRepository.GetByID(ctx, messageID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("get message: %w", err)
+	}
+
+	if message.ChatID != chatID {
+		return nil, ErrMessageNotFound
+	}
+
 	return message, nil
 }
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// newMessagePage converts a page request into a repository window. One extra
+// message is requested so that the presence of a further page can be detected.
+func newMessagePage(req *dto.PageRequest) (repo.MessagePage, error) {
+	page := repo.MessagePage{Limit: req.Limit + 1}
+
+	if req.Before != "" && req.After != "" {
+		return page, ErrInvalidCursor
+	}
+
+	var err error
+	if req.Before != "" {
+		if page.Before, err = decodeCursor(req.Before); err != nil {
+			return page, err
+		}
+	}
+
+	if req.After != "" {
+		if page.After, err = decodeCursor(req.After); err != nil {
+			return page, err
+		}
+	}
+
+	return page, nil
+}
+
+// trimPage drops the look-ahead message fetched by newMessagePage and builds
+// the cursors for the neighbouring pages. Messages are ordered newest first.
+func trimPage(messages []models.Message, req *dto.PageRequest, page repo.MessagePage) ([]models.Message, dto.PageCursors) {
+	hasMore := len(messages) > req.Limit
+
+	if hasMore {
+		if page.After != nil {
+			messages = messages[1:]
+		} else {
+			messages = messages[:req.Limit]
+		}
+	}
+
+	var cursors dto.PageCursors
+	if len(messages) == 0 {
+		return messages, cursors
+	}
+
+	hasOlder := page.After != nil || hasMore
+	hasNewer := page.Before != nil || (page.After != nil && hasMore)
+
+	if hasOlder {
+		cursors.Before = encodeCursor(messages[len(messages)-1])
+	}
+
+	if hasNewer {
+		cursors.After = encodeCursor(messages[0])
+	}
+
+	return messages, cursors
+}
+
+func encodeCursor(message models.Message) string {
+	raw := strconv.FormatInt(message.CreatedAt.UnixMicro(), 10) + ":" + strconv.Itoa(message.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(token string) (*repo.MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	micros, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &repo.MessageCursor{CreatedAt: time.UnixMicro(createdAt), ID: messageID}, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+// permission is an operation inside a chat that depends on the caller's role.
+type permission int
+
+const (
+	permPostMessage permission = iota
+	permEditOwnMessage
+	permDeleteAnyMessage
+	permManageMembers
+	permPinMessage
+	permDeleteChat
+	permTransferOwnership
+	permManageRetention
+)
+
+var rolePermissions = map[string][]permission{
+	models.RoleOwner: {
+		permPostMessage, permEditOwnMessage, permDeleteAnyMessage,
+		permManageMembers, permPinMessage, permDeleteChat, permTransferOwnership,
+		permManageRetention,
+	},
+	models.RoleAdmin: {
+		permPostMessage, permEditOwnMessage, permDeleteAnyMessage,
+		permManageMembers, permPinMessage,
+	},
+	models.RoleMember:   {permPostMessage, permEditOwnMessage},
+	models.RoleReadOnly: {},
+}
+
+// directPermissions apply to both participants of a direct chat whatever
+// their role: neither moderates the other, but each may manage the
+// conversation itself.
+var directPermissions = []permission{
+	permPostMessage, permEditOwnMessage, permPinMessage, permDeleteChat,
+	permManageRetention,
+}
+
+// roleRanks orders roles by privilege. Members may only manage members ranked
+// below themselves and grant roles ranked below their own.
+var roleRanks = map[string]int{
+	models.RoleReadOnly: 0,
+	models.RoleMember:   1,
+	models.RoleAdmin:    2,
+	models.RoleOwner:    3,
+}
+
+func hasPermission(member *models.ChatMember, perm permission) bool {
+	if member.Chat != nil && member.Chat.Kind == models.ChatKindDirect {
+		return slices.Contains(directPermissions, perm)
+	}
+	return slices.Contains(rolePermissions[member.Role], perm)
+}
+
+// canManage reports whether actor may change the role of target or remove it
+// from the chat.
+func canManage(actor *models.ChatMember, target *models.ChatMember) bool {
+	return hasPermission(actor, permManageMembers) && roleRanks[actor.Role] > roleRanks[target.Role]
+}
+
+// canGrant reports whether actor may give role to another member.
+func canGrant(actor *models.ChatMember, role string) bool {
+	rank, ok := roleRanks[role]
+	return ok && hasPermission(actor, permManageMembers) && roleRanks[actor.Role] > rank
+}
+
+// canEditMessage reports whether member may change the text of message. Only
+// authors edit their messages.
+func canEditMessage(member *models.ChatMember, message *models.Message) bool {
+	isAuthor := message.AuthorID != nil && *message.AuthorID == member.UserID
+	return isAuthor && hasPermission(member, permEditOwnMessage)
+}
+
+// canDeleteMessage reports whether member may delete message. Besides the
+// author, moderators may delete any message.
+func canDeleteMessage(member *models.ChatMember, message *models.Message) bool {
+	return canEditMessage(member, message) || hasPermission(member, permDeleteAnyMessage)
+}
+
+// requirePermission returns the caller's membership in a chat if their role
+// grants perm, and ErrForbidden otherwise.
+func requirePermission(ctx context.Context, members repo.MemberRepository, chatID int, perm permission) (*models.ChatMember, error) {
+	member, err := requireMember(ctx, members, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasPermission(member, perm) {
+		return nil, ErrForbidden
+	}
+
+	return member, nil
+}
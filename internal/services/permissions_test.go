@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasPermission(t *testing.T) {
+	owner := &models.ChatMember{Role: models.RoleOwner}
+	admin := &models.ChatMember{Role: models.RoleAdmin}
+	member := &models.ChatMember{Role: models.RoleMember}
+	readOnly := &models.ChatMember{Role: models.RoleReadOnly}
+
+	assert.True(t, hasPermission(owner, permDeleteChat))
+	assert.False(t, hasPermission(admin, permDeleteChat))
+	assert.True(t, hasPermission(owner, permManageRetention))
+	assert.False(t, hasPermission(admin, permManageRetention))
+	assert.True(t, hasPermission(admin, permManageMembers))
+	assert.False(t, hasPermission(member, permManageMembers))
+	assert.True(t, hasPermission(admin, permPinMessage))
+	assert.False(t, hasPermission(member, permPinMessage))
+	assert.True(t, hasPermission(member, permPostMessage))
+	assert.False(t, hasPermission(readOnly, permPostMessage))
+}
+
+func TestHasPermission_DirectChat(t *testing.T) {
+	participant := &models.ChatMember{Role: models.RoleMember, Chat: &models.Chat{Kind: models.ChatKindDirect}}
+
+	assert.True(t, hasPermission(participant, permPostMessage))
+	assert.True(t, hasPermission(participant, permPinMessage))
+	assert.True(t, hasPermission(participant, permDeleteChat))
+	assert.True(t, hasPermission(participant, permManageRetention))
+	assert.False(t, hasPermission(participant, permDeleteAnyMessage))
+	assert.False(t, hasPermission(participant, permManageMembers))
+	assert.False(t, hasPermission(participant, permTransferOwnership))
+}
+
+func TestCanManage(t *testing.T) {
+	owner := &models.ChatMember{UserID: "o", Role: models.RoleOwner}
+	admin := &models.ChatMember{UserID: "a", Role: models.RoleAdmin}
+	otherAdmin := &models.ChatMember{UserID: "b", Role: models.RoleAdmin}
+	member := &models.ChatMember{UserID: "m", Role: models.RoleMember}
+
+	assert.True(t, canManage(owner, admin))
+	assert.True(t, canManage(admin, member))
+	assert.False(t, canManage(admin, otherAdmin))
+	assert.False(t, canManage(admin, owner))
+	assert.False(t, canManage(member, member))
+
+	assert.True(t, canGrant(owner, models.RoleAdmin))
+	assert.False(t, canGrant(admin, models.RoleAdmin))
+	assert.True(t, canGrant(admin, models.RoleReadOnly))
+	assert.False(t, canGrant(owner, "superuser"))
+}
+
+func TestCanModifyMessage(t *testing.T) {
+	author := "m"
+	message := &models.Message{AuthorID: &author}
+
+	member := &models.ChatMember{UserID: "m", Role: models.RoleMember}
+	otherMember := &models.ChatMember{UserID: "x", Role: models.RoleMember}
+	admin := &models.ChatMember{UserID: "a", Role: models.RoleAdmin}
+	demotedAuthor := &models.ChatMember{UserID: "m", Role: models.RoleReadOnly}
+
+	assert.True(t, canEditMessage(member, message))
+	assert.True(t, canDeleteMessage(member, message))
+	assert.False(t, canEditMessage(otherMember, message))
+	assert.False(t, canDeleteMessage(otherMember, message))
+	assert.False(t, canEditMessage(admin, message))
+	assert.True(t, canDeleteMessage(admin, message))
+	assert.False(t, canEditMessage(demotedAuthor, message))
+}
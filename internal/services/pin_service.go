@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+var (
+	ErrPinNotFound      = errors.New("pinned message not found")
+	ErrPinLimitExceeded = errors.New("pinned messages limit exceeded")
+)
+
+type PinService interface {
+	PinMessage(ctx context.Context, chatID int, messageID int) (*models.PinnedMessage, error)
+	UnpinMessage(ctx context.Context, chatID int, messageID int) error
+}
+
+type pinService struct {
+	pinRepository    repo.PinRepository
+	memberRepository repo.MemberRepository
+	maxPinned        int
+}
+
+// NewPinService returns a service that allows at most maxPinned pinned
+// messages per chat.
+func NewPinService(pinRepository repo.PinRepository, memberRepository repo.MemberRepository, maxPinned int) PinService {
+	return &pinService{
+		pinRepository:    pinRepository,
+		memberRepository: memberRepository,
+		maxPinned:        maxPinned,
+	}
+}
+
+// PinMessage pins a message on behalf of the caller. Pinning an already
+// pinned message returns the existing pin.
+func (service *pinService) PinMessage(ctx context.Context, chatID int, messageID int) (*models.PinnedMessage, error) {
+	member, err := requirePermission(ctx, service.memberRepository, chatID, permPinMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	pin := &models.PinnedMessage{ChatID: chatID, MessageID: messageID, PinnedBy: &member.UserID}
+	if err := service.pinRepository.PinMessage(ctx, pin, service.maxPinned); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "pin limit"):
+			return nil, ErrPinLimitExceeded
+		case strings.Contains(err.Error(), "chat with id"):
+			return nil, ErrChatNotFound
+		case strings.Contains(err.Error(), "not found"):
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("pin message: %w", err)
+	}
+
+	return pin, nil
+}
+
+func (service *pinService) UnpinMessage(ctx context.Context, chatID int, messageID int) error {
+	if _, err := requirePermission(ctx, service.memberRepository, chatID, permPinMessage); err != nil {
+		return err
+	}
+
+	if err := service.pinRepository.UnpinMessage(ctx, chatID, messageID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return ErrPinNotFound
+		}
+		return fmt.Errorf("unpin message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/presence"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+type PresenceService interface {
+	Connect(ctx context.Context, chatID int) (func(), error)
+	Typing(ctx context.Context, chatID int) error
+	ListPresence(ctx context.Context, chatID int) ([]models.Presence, error)
+}
+
+type presenceService struct {
+	memberRepository repo.MemberRepository
+	tracker          *presence.Tracker
+}
+
+func NewPresenceService(memberRepository repo.MemberRepository, tracker *presence.Tracker) PresenceService {
+	return &presenceService{
+		memberRepository: memberRepository,
+		tracker:          tracker,
+	}
+}
+
+// Connect marks the caller present in a chat until the returned function is
+// called. Access to the chat must already have been checked.
+func (service *presenceService) Connect(ctx context.Context, chatID int) (func(), error) {
+	caller, err := callerUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.tracker.Connect(chatID, caller.ID), nil
+}
+
+// Typing records a typing heartbeat of the caller. Only members allowed to
+// post may report typing.
+func (service *presenceService) Typing(ctx context.Context, chatID int) error {
+	member, err := requirePermission(ctx, service.memberRepository, chatID, permPostMessage)
+	if err != nil {
+		return err
+	}
+
+	service.tracker.Typing(chatID, member.UserID)
+	return nil
+}
+
+func (service *presenceService) ListPresence(ctx context.Context, chatID int) ([]models.Presence, error) {
+	if _, err := requireMember(ctx, service.memberRepository, chatID); err != nil {
+		return nil, err
+	}
+
+	return service.tracker.List(chatID), nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+// attachReactions fills in the reaction summaries of messages as seen by
+// userID, using one query for the whole batch.
+func attachReactions(ctx context.Context, reactions repo.ReactionRepository, userID string, messages []*models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	summaries, err := reactions.Summarize(ctx, ids, userID)
+	if err != nil {
+		return fmt.Errorf("load reactions: %w", err)
+	}
+
+	for _, message := range messages {
+		message.Reactions = summaries[message.ID]
+	}
+
+	return nil
+}
+
+func messagePointers(messages []models.Message) []*models.Message {
+	pointers := make([]*models.Message, len(messages))
+	for i := range messages {
+		pointers[i] = &messages[i]
+	}
+	return pointers
+}
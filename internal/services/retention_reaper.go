@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+const (
+	// reapInterval is how often expired messages are deleted. Expired messages
+	// are hidden from readers in the meantime.
+	reapInterval = 10 * time.Second
+
+	// reapBatchSize bounds the number of messages deleted per statement so that
+	// large backlogs do not hold locks for long.
+	reapBatchSize = 500
+)
+
+// RetentionReaper deletes messages past their expires_at or the retention
+// policy of their chat.
+type RetentionReaper struct {
+	messageRepository repo.MessageRepository
+}
+
+func NewRetentionReaper(messageRepository repo.MessageRepository) *RetentionReaper {
+	return &RetentionReaper{messageRepository: messageRepository}
+}
+
+// Run deletes expired messages until ctx is cancelled.
+func (r *RetentionReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		r.reap(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reap deletes batches until a batch comes back short of the limit.
+func (r *RetentionReaper) reap(ctx context.Context) {
+	for ctx.Err() == nil {
+		deleted, err := r.messageRepository.DeleteExpired(ctx, reapBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to delete expired messages", "error", err)
+			}
+			return
+		}
+
+		if deleted > 0 {
+			slog.Info("Deleted expired messages", "count", deleted)
+		}
+
+		if deleted < reapBatchSize {
+			return
+		}
+	}
+}
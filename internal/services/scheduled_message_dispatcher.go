@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/events"
+	"github.com/jonx8/chat-service/internal/models"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+const (
+	// dispatchInterval is how often due scheduled messages are looked for.
+	dispatchInterval = time.Second
+
+	// dispatchBatchSize is the number of scheduled messages posted per
+	// transaction.
+	dispatchBatchSize = 50
+)
+
+// ScheduledMessageDispatcher posts scheduled messages once they are due and
+// publishes them like messages created directly. Messages whose author can no
+// longer post in the chat are discarded.
+type ScheduledMessageDispatcher struct {
+	scheduledRepository repo.ScheduledMessageRepository
+	memberRepository    repo.MemberRepository
+	publisher           events.Publisher
+}
+
+func NewScheduledMessageDispatcher(
+	scheduledRepository repo.ScheduledMessageRepository,
+	memberRepository repo.MemberRepository,
+	publisher events.Publisher,
+) *ScheduledMessageDispatcher {
+	return &ScheduledMessageDispatcher{
+		scheduledRepository: scheduledRepository,
+		memberRepository:    memberRepository,
+		publisher:           publisher,
+	}
+}
+
+// Run delivers due messages until ctx is cancelled. A batch interrupted by
+// cancellation is rolled back and delivered by the next run.
+func (d *ScheduledMessageDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *ScheduledMessageDispatcher) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := d.scheduledRepository.DeliverDue(ctx, dispatchBatchSize, func(scheduled *models.ScheduledMessage) (bool, error) {
+			return d.canPost(ctx, scheduled)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to deliver scheduled messages", "error", err)
+			}
+			return
+		}
+
+		for i := range messages {
+			d.publisher.Publish(events.Event{
+				ID:     messages[i].ID,
+				Type:   events.TypeMessageCreated,
+				ChatID: messages[i].ChatID,
+				Data:   &messages[i],
+			})
+		}
+
+		if len(messages) < dispatchBatchSize {
+			return
+		}
+	}
+}
+
+func (d *ScheduledMessageDispatcher) canPost(ctx context.Context, scheduled *models.ScheduledMessage) (bool, error) {
+	member, err := d.memberRepository.GetMember(ctx, scheduled.ChatID, scheduled.AuthorID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not a member") || strings.Contains(err.Error(), "not found") {
+			slog.Warn("Discarding scheduled message of a former member", "scheduledID", scheduled.ID, "chatID", scheduled.ChatID)
+			return false, nil
+		}
+		return false, fmt.Errorf("check membership: %w", err)
+	}
+
+	if !hasPermission(member, permPostMessage) {
+		slog.Warn("Discarding scheduled message of a member who may not post", "scheduledID", scheduled.ID, "chatID", scheduled.ChatID)
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonx8/chat-service/internal/dto"
+	repo "github.com/jonx8/chat-service/internal/repositories"
+)
+
+type SearchService interface {
+	SearchMessages(ctx context.Context, req *dto.SearchRequest) (*dto.SearchResult, error)
+}
+
+type searchService struct {
+	searchRepository repo.SearchRepository
+}
+
+func NewSearchService(searchRepository repo.SearchRepository) SearchService {
+	return &searchService{searchRepository: searchRepository}
+}
+
+func (service *searchService) SearchMessages(ctx context.Context, req *dto.SearchRequest) (*dto.SearchResult, error) {
+	caller, err := callerUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, total, err := service.searchRepository.SearchMessages(ctx, repo.SearchParams{
+		UserID: caller.ID,
+		Query:  req.Query,
+		ChatID: req.ChatID,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search messages: %w", err)
+	}
+
+	return &dto.SearchResult{Hits: hits, Total: total}, nil
+}
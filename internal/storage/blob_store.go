@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jonx8/chat-service/internal/config"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps the content of uploaded files. Keys are generated by the
+// service and may contain slashes.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the blob store selected by cfg.StorageBackend.
+func New(cfg *config.Config) (BlobStore, error) {
+	switch cfg.StorageBackend {
+	case "local":
+		return NewLocalStore(cfg.StorageDir)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
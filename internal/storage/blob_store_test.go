@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is an in-memory stand-in for an S3-compatible service that supports
+// the requests issued by s3Store.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		content, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = content
+	case http.MethodGet, http.MethodHead:
+		content, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	content := "0123456789abcdef"
+
+	// Put and read back
+	err := store.Put(ctx, "chats/1/blob", strings.NewReader(content), int64(len(content)), "text/plain")
+	require.NoError(t, err)
+
+	blob, err := store.Open(ctx, "chats/1/blob")
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	// Seek as http.ServeContent does for Range requests
+	size, err := blob.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+
+	_, err = blob.Seek(10, io.SeekStart)
+	require.NoError(t, err)
+
+	part := make([]byte, 4)
+	_, err = io.ReadFull(blob, part)
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", string(part))
+	assert.NoError(t, blob.Close())
+
+	// Delete
+	require.NoError(t, store.Delete(ctx, "chats/1/blob"))
+
+	_, err = store.Open(ctx, "chats/1/blob")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+func TestLocalStore_RejectsKeysOutsideRoot(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "../escape", strings.NewReader("x"), 1, "text/plain")
+	assert.Error(t, err)
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Store(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "attachments",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+func TestS3Store_PathStyleKeys(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Store(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "attachments",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "chats/7/blob", strings.NewReader("x"), 1, "text/plain")
+	require.NoError(t, err)
+
+	assert.Contains(t, fake.objects, "/attachments/chats/7/blob")
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// unsignedPayload lets uploads be streamed without hashing the body first.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+type S3Options struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.amazonaws.com
+	// or http://localhost:9000 for MinIO. Buckets are addressed path-style.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+type s3Store struct {
+	endpoint *url.URL
+	options  S3Options
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store stores blobs in a bucket of an S3-compatible service. Requests
+// are signed with AWS Signature Version 4.
+func NewS3Store(options S3Options) (BlobStore, error) {
+	if options.Endpoint == "" || options.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", options.Endpoint)
+	}
+
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &s3Store{endpoint: endpoint, options: options, client: client, now: time.Now}, nil
+}
+
+func (store *s3Store) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	req, err := store.newRequest(ctx, http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := store.do(req)
+	if err != nil {
+		return fmt.Errorf("put blob: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Open returns a reader that fetches the object lazily, issuing a ranged GET
+// after every seek. This lets http.ServeContent answer Range requests without
+// downloading the whole object.
+func (store *s3Store) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	req, err := store.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := store.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("open blob: %w", err)
+	}
+	resp.Body.Close()
+
+	return &s3Object{ctx: ctx, store: store, key: key, size: resp.ContentLength}, nil
+}
+
+func (store *s3Store) Delete(ctx context.Context, key string) error {
+	req, err := store.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := store.do(req)
+	if err != nil && !errors.Is(err, ErrBlobNotFound) {
+		return fmt.Errorf("delete blob: %w", err)
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+func (store *s3Store) newRequest(ctx context.Context, method string, key string, body io.Reader) (*http.Request, error) {
+	if key == "" {
+		return nil, errors.New("empty blob key")
+	}
+
+	target := *store.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + store.options.Bucket + "/" + key
+	target.RawPath = escapePath(target.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 request: %w", err)
+	}
+
+	return req, nil
+}
+
+// do signs and sends a request. Responses other than 2xx and 206 are turned
+// into errors, with 404 reported as ErrBlobNotFound.
+func (store *s3Store) do(req *http.Request) (*http.Response, error) {
+	store.sign(req)
+
+	resp, err := store.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBlobNotFound
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+}
+
+// sign adds an AWS Signature Version 4 Authorization header. Only the host
+// and the x-amz-* headers are signed.
+func (store *s3Store) sign(req *http.Request) {
+	now := store.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + unsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := day + "/" + store.options.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+store.options.SecretAccessKey), day)
+	key = hmacSHA256(key, store.options.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.options.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// escapePath percent-encodes everything but unreserved characters and
+// slashes, as required for the canonical URI of S3 requests.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// s3Object reads an object starting at offset. The body of the current GET is
+// kept open until the next seek to a different position.
+type s3Object struct {
+	ctx    context.Context
+	store  *s3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (object *s3Object) Read(p []byte) (int, error) {
+	if object.offset >= object.size {
+		return 0, io.EOF
+	}
+
+	if object.body == nil {
+		req, err := object.store.newRequest(object.ctx, http.MethodGet, object.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", object.offset))
+
+		resp, err := object.store.do(req)
+		if err != nil {
+			return 0, fmt.Errorf("read blob: %w", err)
+		}
+		object.body = resp.Body
+	}
+
+	n, err := object.body.Read(p)
+	object.offset += int64(n)
+	return n, err
+}
+
+func (object *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = object.offset + offset
+	case io.SeekEnd:
+		next = object.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if next < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if next != object.offset {
+		object.closeBody()
+		object.offset = next
+	}
+
+	return next, nil
+}
+
+func (object *s3Object) Close() error {
+	object.closeBody()
+	return nil
+}
+
+func (object *s3Object) closeBody() {
+	if object.body != nil {
+		object.body.Close()
+		object.body = nil
+	}
+}
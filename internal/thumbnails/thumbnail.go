@@ -0,0 +1,116 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"slices"
+)
+
+// ContentType is the media type of generated thumbnails. PNG keeps the
+// transparency of PNG and GIF sources.
+const ContentType = "image/png"
+
+// maxPixels bounds the size of decoded images, so that a small compressed
+// file cannot exhaust memory.
+const maxPixels = 50_000_000
+
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+var supportedTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// Supported reports whether thumbnails can be generated for contentType.
+func Supported(contentType string) bool {
+	return slices.Contains(supportedTypes, contentType)
+}
+
+// Thumbnail is a PNG-encoded preview of an image.
+type Thumbnail struct {
+	Data []byte
+
+	// Width and Height are the dimensions of the source image.
+	Width  int
+	Height int
+}
+
+// Generate decodes a PNG, JPEG or GIF image and scales it down to fit into a
+// maxDimension square. Smaller images keep their size. Only the first frame
+// of animated GIFs is used.
+func Generate(source io.ReadSeeker, maxDimension int) (*Thumbnail, error) {
+	config, _, err := image.DecodeConfig(source)
+	if err != nil {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	if _, err := source.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("rewind image: %w", err)
+	}
+
+	img, _, err := image.Decode(source)
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleDown(img, maxDimension)); err != nil {
+		return nil, fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &Thumbnail{Data: buf.Bytes(), Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
+
+// scaleDown resizes img with a box filter: every pixel of the result is the
+// average of the source pixels it covers.
+func scaleDown(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > maxDimension || srcH > maxDimension {
+		if srcW >= srcH {
+			dstW, dstH = maxDimension, max(1, srcH*maxDimension/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*maxDimension/srcH), maxDimension
+		}
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dstW, dstH))
+	for dy := 0; dy < dstH; dy++ {
+		y0 := bounds.Min.Y + dy*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(dy+1)*srcH/dstH)
+
+		for dx := 0; dx < dstW; dx++ {
+			x0 := bounds.Min.X + dx*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(dx+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					pr, pg, pb, pa := img.At(x, y).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(dx, dy, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
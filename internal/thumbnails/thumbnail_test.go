@@ -0,0 +1,69 @@
+package thumbnails
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	return img
+}
+
+func TestGenerate_ScalesDownKeepingAspectRatio(t *testing.T) {
+	encoders := map[string]func(*bytes.Buffer, image.Image) error{
+		"png":  func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) },
+		"jpeg": func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) },
+		"gif":  func(buf *bytes.Buffer, img image.Image) error { return gif.Encode(buf, img, nil) },
+	}
+
+	for name, encode := range encoders {
+		t.Run(name, func(t *testing.T) {
+			var source bytes.Buffer
+			require.NoError(t, encode(&source, newTestImage(400, 200)))
+
+			thumbnail, err := Generate(bytes.NewReader(source.Bytes()), 100)
+			require.NoError(t, err)
+
+			assert.Equal(t, 400, thumbnail.Width)
+			assert.Equal(t, 200, thumbnail.Height)
+
+			decoded, err := png.Decode(bytes.NewReader(thumbnail.Data))
+			require.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+		})
+	}
+}
+
+func TestGenerate_KeepsSmallImages(t *testing.T) {
+	var source bytes.Buffer
+	require.NoError(t, png.Encode(&source, newTestImage(30, 60)))
+
+	thumbnail, err := Generate(bytes.NewReader(source.Bytes()), 100)
+	require.NoError(t, err)
+
+	decoded, err := png.Decode(bytes.NewReader(thumbnail.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 30, 60), decoded.Bounds())
+
+	r, g, b, a := decoded.At(10, 10).RGBA()
+	assert.Equal(t, []uint32{200, 100, 50, 255}, []uint32{r >> 8, g >> 8, b >> 8, a >> 8})
+}
+
+func TestGenerate_RejectsNonImages(t *testing.T) {
+	_, err := Generate(bytes.NewReader([]byte("not an image")), 100)
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jonx8/chat-service/internal/models"
+	"github.com/jonx8/chat-service/internal/repositories"
+	"github.com/jonx8/chat-service/internal/storage"
+)
+
+const (
+	// pollInterval is how often the worker looks for new images.
+	pollInterval = 2 * time.Second
+
+	// batchSize is the number of images claimed at once.
+	batchSize = 10
+
+	// claimLease is how long claimed images are left to this worker. Images it
+	// has not processed by then are picked up again.
+	claimLease = time.Minute
+)
+
+// Worker generates thumbnails for image attachments in the background. New
+// uploads are picked up from the database, so thumbnails of images uploaded
+// before a restart are generated as well.
+type Worker struct {
+	attachments  repositories.AttachmentRepository
+	blobStore    storage.BlobStore
+	maxDimension int
+}
+
+func NewWorker(attachments repositories.AttachmentRepository, blobStore storage.BlobStore, maxDimension int) *Worker {
+	return &Worker{
+		attachments:  attachments,
+		blobStore:    blobStore,
+		maxDimension: maxDimension,
+	}
+}
+
+// Run processes pending images until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processPending works through pending images batch by batch. Images whose
+// thumbnail could not be stored stay pending and are retried once their claim
+// expires.
+func (w *Worker) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		attachments, err := w.attachments.ClaimPendingThumbnails(ctx, batchSize, claimLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to claim pending thumbnails", "error", err)
+			}
+			return
+		}
+
+		processed := 0
+		for i := range attachments {
+			if w.process(ctx, &attachments[i]) {
+				processed++
+			}
+		}
+
+		if len(attachments) < batchSize || processed == 0 {
+			return
+		}
+	}
+}
+
+// process generates and stores the thumbnail of one attachment. It reports
+// whether the attachment is no longer pending.
+func (w *Worker) process(ctx context.Context, attachment *models.Attachment) bool {
+	content, err := w.blobStore.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return w.markFailed(ctx, attachment, err)
+		}
+		slog.Error("Failed to open image", "error", err, "attachmentID", attachment.ID)
+		return false
+	}
+
+	thumbnail, err := Generate(content, w.maxDimension)
+	content.Close()
+
+	if err != nil {
+		return w.markFailed(ctx, attachment, err)
+	}
+
+	key, err := thumbnailKey(attachment)
+	if err != nil {
+		slog.Error("Failed to store thumbnail", "error", err, "attachmentID", attachment.ID)
+		return false
+	}
+
+	err = w.blobStore.Put(ctx, key, bytes.NewReader(thumbnail.Data), int64(len(thumbnail.Data)), ContentType)
+	if err != nil {
+		slog.Error("Failed to store thumbnail", "error", err, "attachmentID", attachment.ID)
+		return false
+	}
+
+	attachment.Width = &thumbnail.Width
+	attachment.Height = &thumbnail.Height
+	attachment.ThumbnailKey = &key
+
+	if err := w.attachments.SaveThumbnail(ctx, attachment); err != nil {
+		if !strings.Contains(err.Error(), "no longer pending") {
+			slog.Error("Failed to save thumbnail", "error", err, "attachmentID", attachment.ID)
+			return false
+		}
+
+		if err := w.blobStore.Delete(ctx, key); err != nil {
+			slog.Error("Failed to delete discarded thumbnail", "error", err, "key", key)
+		}
+	}
+
+	return true
+}
+
+// thumbnailKey returns a new key for a thumbnail of attachment. Every attempt
+// gets its own key: a worker whose claim has expired may still be storing a
+// thumbnail of the same image, and the one that loses the race to
+// SaveThumbnail must not delete the blob of the winner.
+func thumbnailKey(attachment *models.Attachment) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate thumbnail key: %w", err)
+	}
+
+	return fmt.Sprintf("%s.%s.thumbnail.png", attachment.StorageKey, hex.EncodeToString(buf)), nil
+}
+
+func (w *Worker) markFailed(ctx context.Context, attachment *models.Attachment, cause error) bool {
+	slog.Warn("Failed to generate thumbnail", "error", cause, "attachmentID", attachment.ID)
+
+	if err := w.attachments.MarkThumbnailFailed(ctx, attachment.ID); err != nil {
+		slog.Error("Failed to mark thumbnail as failed", "error", err, "attachmentID", attachment.ID)
+		return false
+	}
+
+	return true
+}
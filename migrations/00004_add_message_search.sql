@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE messages
+    ADD COLUMN text_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', text)) STORED;
+
+CREATE INDEX idx_messages_text_tsv ON messages USING GIN (text_tsv);
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_messages_text_tsv;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS text_tsv;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE users (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+ALTER TABLE chats
+    ADD COLUMN owner_id VARCHAR(255) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE messages
+    ADD COLUMN author_id VARCHAR(255) REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_chats_owner_id ON chats(owner_id);
+CREATE INDEX idx_messages_author_id ON messages(author_id);
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE messages DROP COLUMN IF EXISTS author_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS users;
+
+-- +goose StatementEnd
\ No newline at end of file
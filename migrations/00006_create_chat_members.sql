@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE chat_members (
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX idx_chat_members_user_id ON chat_members(user_id);
+
+-- Owners and everyone who already posted keep access to their chats.
+INSERT INTO chat_members (chat_id, user_id)
+SELECT id, owner_id FROM chats WHERE owner_id IS NOT NULL
+UNION
+SELECT DISTINCT chat_id, author_id FROM messages WHERE author_id IS NOT NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS chat_members;
+
+-- +goose StatementEnd
\ No newline at end of file
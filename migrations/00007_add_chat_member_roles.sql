@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE chat_members
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'member'
+        CHECK (role IN ('owner', 'admin', 'member', 'read_only'));
+
+UPDATE chat_members cm
+SET role = 'owner'
+FROM chats c
+WHERE c.id = cm.chat_id AND c.owner_id = cm.user_id;
+
+-- A chat has at most one owner; ownership transfers demote the old owner first.
+CREATE UNIQUE INDEX idx_chat_members_owner ON chat_members(chat_id) WHERE role = 'owner';
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_chat_members_owner;
+ALTER TABLE chat_members DROP COLUMN IF EXISTS role;
+
+-- +goose StatementEnd
\ No newline at end of file
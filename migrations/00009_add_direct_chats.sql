@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE chats
+    ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'group' CHECK (kind IN ('group', 'direct')),
+    ADD COLUMN direct_low_user_id VARCHAR(255) REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN direct_high_user_id VARCHAR(255) REFERENCES users(id) ON DELETE SET NULL,
+    ADD CONSTRAINT chk_chats_direct_pair CHECK (kind = 'group' OR direct_low_user_id < direct_high_user_id);
+
+-- Participants are stored in sorted order so that each pair of users has at
+-- most one direct chat regardless of who opened it.
+CREATE UNIQUE INDEX idx_chats_direct_pair ON chats(direct_low_user_id, direct_high_user_id)
+    WHERE kind = 'direct';
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_chats_direct_pair;
+ALTER TABLE chats
+    DROP CONSTRAINT IF EXISTS chk_chats_direct_pair,
+    DROP COLUMN IF EXISTS direct_high_user_id,
+    DROP COLUMN IF EXISTS direct_low_user_id,
+    DROP COLUMN IF EXISTS kind;
+
+-- +goose StatementEnd
\ No newline at end of file
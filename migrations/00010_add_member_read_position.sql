@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE chat_members
+    ADD COLUMN last_read_message_id INT REFERENCES messages(id) ON DELETE SET NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE chat_members DROP COLUMN IF EXISTS last_read_message_id;
+
+-- +goose StatementEnd
\ No newline at end of file
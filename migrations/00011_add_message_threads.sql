@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE messages ADD COLUMN parent_id INT REFERENCES messages(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_messages_parent_id_created_at ON messages(parent_id, created_at, id)
+    WHERE parent_id IS NOT NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_messages_parent_id_created_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS parent_id;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE attachments
+    ADD COLUMN width INT,
+    ADD COLUMN height INT,
+    ADD COLUMN thumbnail_key VARCHAR(512),
+    ADD COLUMN thumbnail_status VARCHAR(16)
+        CONSTRAINT chk_attachments_thumbnail_status CHECK (thumbnail_status IN ('pending', 'ready', 'failed'));
+
+CREATE INDEX idx_attachments_thumbnail_pending ON attachments(id) WHERE thumbnail_status = 'pending';
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_attachments_thumbnail_pending;
+
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS thumbnail_status,
+    DROP COLUMN IF EXISTS thumbnail_key,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width;
+
+-- +goose StatementEnd
\ No newline at end of file
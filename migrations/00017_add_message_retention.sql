@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE chats
+    ADD COLUMN retention_max_age INT
+        CONSTRAINT chk_chats_retention_max_age CHECK (retention_max_age > 0),
+    ADD COLUMN retention_max_messages INT
+        CONSTRAINT chk_chats_retention_max_messages CHECK (retention_max_messages > 0);
+
+ALTER TABLE messages ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE scheduled_messages ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_messages_expires_at ON messages(expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_messages_expires_at;
+
+ALTER TABLE scheduled_messages DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS retention_max_messages,
+    DROP COLUMN IF EXISTS retention_max_age;
+
+-- +goose StatementEnd
\ No newline at end of file
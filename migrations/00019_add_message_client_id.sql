@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE messages ADD COLUMN client_id UUID;
+
+CREATE UNIQUE INDEX idx_messages_client_id ON messages(chat_id, author_id, client_id)
+    WHERE client_id IS NOT NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_messages_client_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS client_id;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The service sorts the pair by bytes, which only matches the "C" collation;
+-- under the database default "Bob" < "alice" may compare the other way round.
+ALTER TABLE chats
+    DROP CONSTRAINT IF EXISTS chk_chats_direct_pair,
+    ADD CONSTRAINT chk_chats_direct_pair
+        CHECK (kind = 'group' OR direct_low_user_id < direct_high_user_id COLLATE "C");
+
+DROP INDEX IF EXISTS idx_chats_direct_pair;
+CREATE UNIQUE INDEX idx_chats_direct_pair
+    ON chats(direct_low_user_id COLLATE "C", direct_high_user_id COLLATE "C")
+    WHERE kind = 'direct';
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_chats_direct_pair;
+CREATE UNIQUE INDEX idx_chats_direct_pair ON chats(direct_low_user_id, direct_high_user_id)
+    WHERE kind = 'direct';
+
+ALTER TABLE chats
+    DROP CONSTRAINT IF EXISTS chk_chats_direct_pair,
+    ADD CONSTRAINT chk_chats_direct_pair CHECK (kind = 'group' OR direct_low_user_id < direct_high_user_id);
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Expired messages are kept as tombstones, which the reaper no longer needs
+-- to find.
+DROP INDEX IF EXISTS idx_messages_expires_at;
+CREATE INDEX idx_messages_expires_at ON messages(expires_at)
+    WHERE expires_at IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_messages_expires_at;
+CREATE INDEX idx_messages_expires_at ON messages(expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose StatementEnd
\ No newline at end of file
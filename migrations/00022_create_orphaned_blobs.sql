@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE orphaned_blobs (
+    storage_key VARCHAR(512) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Attachments are deleted with their message or chat by cascades the service
+-- never sees, so their blobs are queued here and removed by the blob sweeper
+-- once the deleting transaction has committed.
+CREATE FUNCTION queue_attachment_blobs() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO orphaned_blobs (storage_key) VALUES (OLD.storage_key)
+        ON CONFLICT DO NOTHING;
+
+    IF OLD.thumbnail_key IS NOT NULL THEN
+        INSERT INTO orphaned_blobs (storage_key) VALUES (OLD.thumbnail_key)
+            ON CONFLICT DO NOTHING;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_attachments_queue_blobs AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_attachment_blobs();
+
+CREATE INDEX idx_attachments_unlinked ON attachments(created_at) WHERE message_id IS NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_attachments_unlinked;
+DROP TRIGGER IF EXISTS trg_attachments_queue_blobs ON attachments;
+DROP FUNCTION IF EXISTS queue_attachment_blobs();
+DROP TABLE IF EXISTS orphaned_blobs;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The read position is kept as the (created_at, id) of the last read message
+-- rather than a reference to it, so that it survives the message being
+-- deleted and keeps ordering unread messages.
+ALTER TABLE chat_members ADD COLUMN last_read_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE chat_members cm
+SET last_read_at = m.created_at
+FROM messages m
+WHERE m.id = cm.last_read_message_id;
+
+ALTER TABLE chat_members
+    DROP CONSTRAINT IF EXISTS chat_members_last_read_message_id_fkey,
+    ADD CONSTRAINT chk_chat_members_read_position
+        CHECK ((last_read_at IS NULL) = (last_read_message_id IS NULL));
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+UPDATE chat_members cm
+SET last_read_message_id = NULL
+WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = cm.last_read_message_id);
+
+ALTER TABLE chat_members
+    DROP CONSTRAINT IF EXISTS chk_chat_members_read_position,
+    ADD CONSTRAINT chat_members_last_read_message_id_fkey
+        FOREIGN KEY (last_read_message_id) REFERENCES messages(id) ON DELETE SET NULL,
+    DROP COLUMN IF EXISTS last_read_at;
+
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Group chats created before owners were recorded have no owner, so nobody
+-- could manage them. Their first poster among the members takes ownership.
+-- Chats without an owner and without authored messages got no members in
+-- 00006 and stay inaccessible: there is nobody they could be given to.
+WITH first_posters AS (
+    SELECT DISTINCT ON (m.chat_id) m.chat_id, m.author_id
+    FROM messages m
+    JOIN chats c ON c.id = m.chat_id
+    JOIN chat_members cm ON cm.chat_id = m.chat_id AND cm.user_id = m.author_id
+    WHERE c.kind = 'group' AND c.owner_id IS NULL
+    ORDER BY m.chat_id, m.created_at, m.id
+),
+promoted AS (
+    UPDATE chat_members cm
+    SET role = 'owner'
+    FROM first_posters fp
+    WHERE cm.chat_id = fp.chat_id AND cm.user_id = fp.author_id
+        AND NOT EXISTS (
+            SELECT 1 FROM chat_members o WHERE o.chat_id = cm.chat_id AND o.role = 'owner'
+        )
+    RETURNING cm.chat_id, cm.user_id
+)
+UPDATE chats c
+SET owner_id = p.user_id
+FROM promoted p
+WHERE c.id = p.chat_id;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+
+-- Ownership taken over by the backfill is indistinguishable from ownership
+-- transferred later, so it is kept.
\ No newline at end of file
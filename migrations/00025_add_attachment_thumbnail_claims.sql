@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A worker claims pending images until thumbnail_claimed_until, so that each
+-- image is decoded by a single instance. The claim of a worker that stopped
+-- expires and the image is picked up again.
+ALTER TABLE attachments ADD COLUMN thumbnail_claimed_until TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE attachments DROP COLUMN IF EXISTS thumbnail_claimed_until;
+
+-- +goose StatementEnd
\ No newline at end of file